@@ -0,0 +1,192 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type _ACCESSFORMAT int8
+
+const (
+	// ACCESS_COMMON Apache Common Log Format
+	// Apache通用日志格式
+	ACCESS_COMMON _ACCESSFORMAT = 0
+
+	// ACCESS_COMBINED Apache Combined Log Format, Common Log Format with referer and user agent
+	// Apache组合日志格式，在通用日志格式基础上增加referer与user agent
+	ACCESS_COMBINED _ACCESSFORMAT = 1
+
+	// ACCESS_JSON one JSON object per request, including the latency
+	// 每个请求输出一个JSON对象，包含请求耗时
+	ACCESS_JSON _ACCESSFORMAT = 2
+)
+
+const _CLF_TIMEFORMAT = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogOption configures the access log middleware.
+// AccessLogOption 访问日志中间件的配置
+type AccessLogOption struct {
+	Format    _ACCESSFORMAT // Line format: ACCESS_COMMON, ACCESS_COMBINED or ACCESS_JSON / 日志格式
+	Sample2xx int           // Log only one out of every Sample2xx 2xx responses, <= 1 logs all / 2xx响应每Sample2xx条记录一条，小于等于1时全部记录
+}
+
+// AccessLogMiddleware returns a net/http middleware that writes one line per request to l.
+// Responses with status 5xx are logged at ERROR, 4xx at WARN and all others at INFO. A request whose handler
+// panics is logged with status 500 before the panic goes on. The request context is passed along, so registered
+// context extractors such as trace_id apply. Access lines carry no caller file, as they are not logged by user code.
+//
+// AccessLogMiddleware 返回net/http中间件，每个请求向l写入一条访问日志。
+// 5xx响应以ERROR级别记录，4xx以WARN级别记录，其他以INFO级别记录；handler发生panic时以状态码500记录后继续panic。
+// 访问日志不输出调用者文件信息。
+//
+// e.g.
+//
+//	handler := logger.AccessLogMiddleware(log, &logger.AccessLogOption{Format: logger.ACCESS_COMBINED})(mux)
+func AccessLogMiddleware(l *Logging, option *AccessLogOption) func(http.Handler) http.Handler {
+	if option == nil {
+		option = &AccessLogOption{}
+	}
+	var count atomic.Int64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &accessResponseWriter{ResponseWriter: w}
+			defer func() {
+				status := rw.status
+				p := recover()
+				if p != nil {
+					status = http.StatusInternalServerError
+					defer panic(p)
+				} else if status == 0 {
+					status = http.StatusOK
+				}
+				level := LEVEL_INFO
+				switch {
+				case status >= 500:
+					level = LEVEL_ERROR
+				case status >= 400:
+					level = LEVEL_WARN
+				case status >= 200 && status < 300 && option.Sample2xx > 1:
+					if count.Add(1)%int64(option.Sample2xx) != 1 {
+						return
+					}
+				}
+				line := accessLine(option.Format, r, status, rw.bytes, start)
+				l.printlnctx(r.Context(), nil, level, _NOCALLER, line)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func accessLine(format _ACCESSFORMAT, r *http.Request, status int, size int64, start time.Time) string {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	} else if name, _, ok := r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	if format == ACCESS_JSON {
+		bs, _ := json.Marshal(&struct {
+			Time      string  `json:"time"`
+			Remote    string  `json:"remote"`
+			User      string  `json:"user"`
+			Method    string  `json:"method"`
+			URI       string  `json:"uri"`
+			Proto     string  `json:"proto"`
+			Status    int     `json:"status"`
+			Bytes     int64   `json:"bytes"`
+			Latency   float64 `json:"latency_ms"`
+			Referer   string  `json:"referer,omitempty"`
+			UserAgent string  `json:"user_agent,omitempty"`
+		}{start.Format(time.RFC3339Nano), host, user, r.Method, r.RequestURI, r.Proto, status, size, float64(time.Since(start).Microseconds()) / 1e3, r.Referer(), r.UserAgent()})
+		return string(bs)
+	}
+	bs := make([]byte, 0, 256)
+	bs = append(bs, host...)
+	bs = append(bs, " - "...)
+	bs = append(bs, user...)
+	bs = append(bs, " ["...)
+	bs = start.AppendFormat(bs, _CLF_TIMEFORMAT)
+	bs = append(bs, "] "...)
+	bs = strconv.AppendQuote(bs, r.Method+" "+r.RequestURI+" "+r.Proto)
+	bs = append(bs, ' ')
+	bs = strconv.AppendInt(bs, int64(status), 10)
+	bs = append(bs, ' ')
+	if size > 0 {
+		bs = strconv.AppendInt(bs, size, 10)
+	} else {
+		bs = append(bs, '-')
+	}
+	if format == ACCESS_COMBINED {
+		bs = append(bs, ' ')
+		bs = appendQuoteOrDash(bs, r.Referer())
+		bs = append(bs, ' ')
+		bs = appendQuoteOrDash(bs, r.UserAgent())
+	}
+	return string(bs)
+}
+
+func appendQuoteOrDash(bs []byte, s string) []byte {
+	if s == "" {
+		return append(bs, `"-"`...)
+	}
+	return strconv.AppendQuote(bs, s)
+}
+
+type accessResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessResponseWriter) Write(bs []byte) (n int, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err = w.ResponseWriter.Write(bs)
+	w.bytes += int64(n)
+	return
+}
+
+func (w *accessResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijack not supported")
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by http.ResponseController.
+func (w *accessResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
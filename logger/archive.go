@@ -0,0 +1,63 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const _ARCHIVE_LAYOUT = "2006/01/02"
+
+// backupTime returns the time of the period being backed up: the start of the period of the last write
+// in time modes, now otherwise.
+func (t *fileHandler) backupTime() time.Time {
+	if t._cutmode&_TIMEMODE == _TIMEMODE && t._lastPrint > 0 {
+		return t.periodStart(time.Unix(t._lastPrint, 0))
+	}
+	return loctime()
+}
+
+// archiveRoot returns the directory that holds the backups: the archive directory, resolved against
+// the log directory when relative, or the log directory itself. ROTATE_LINK never moves its files.
+func (t *fileHandler) archiveRoot() string {
+	if t._archivedir == "" || t._rotate == ROTATE_LINK {
+		return t._fileDir
+	}
+	if filepath.IsAbs(t._archivedir) {
+		return filepath.Clean(t._archivedir)
+	}
+	return filepath.Join(t._fileDir, t._archivedir)
+}
+
+// backupDir returns the directory the backup of period tm goes to, with its YYYY/MM/DD subfolder if enabled.
+func (t *fileHandler) backupDir(tm time.Time) string {
+	dir := t.archiveRoot()
+	if t._archivedated && dir != t._fileDir {
+		dir = filepath.Join(dir, filepath.FromSlash(tm.Format(_ARCHIVE_LAYOUT)))
+	}
+	return dir
+}
+
+// moveFile renames src to dst, falling back to copy and remove when they are on different file systems.
+// The copy is removed again if src cannot be, so that the backup is not kept twice.
+func moveFile(src, dst string) (err error) {
+	if err = os.Rename(src, dst); !errors.Is(err, syscall.EXDEV) {
+		return
+	}
+	if err = copyFile(src, dst); err != nil {
+		return
+	}
+	if err = os.Remove(src); err != nil {
+		os.Remove(dst)
+	}
+	return
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _DEFAULT_SEQWIDTH = 3
+
+const (
+	_PART_TEXT = iota
+	_PART_NAME
+	_PART_EXT
+	_PART_DATE
+	_PART_SEQ
+)
+
+type templatePart struct {
+	kind  int
+	text  string // literal text, or the time layout of a date placeholder
+	width int    // minimum digits of a seq placeholder
+}
+
+// backupTemplate is a parsed backup file name template. The placeholders are
+//
+//	{name}         the log file name without its extension
+//	{ext}          the extension of the log file name, including the dot
+//	{date}         the start of the rotation period in ISO style: 2006-01-02, 2006-01-02T15 for MODE_HOUR,
+//	               2006-01 for MODE_MONTH, 2006-01-02T1504 for MODE_MINUTE and cron expressions
+//	{date:layout}  the rotation period formatted with a Go time layout
+//	{seq}          the sequence number within the period, zero-padded to 3 digits
+//	{seq:n}        the sequence number zero-padded to n digits
+//
+// A template without {seq} gets ".{seq}" before {ext}, or at its end, so that backup names never collide.
+// The same template is compiled into the regular expression used to find the backups for retention.
+type backupTemplate struct {
+	parts []templatePart
+	re    *regexp.Regexp
+	date  int // submatch index of the first date, 0 if absent
+	seq   int // submatch index of the first sequence
+	mode  _MODE_TIME
+	name  string // the log file name
+	zext  string // the suffix of compressed backups
+}
+
+// parseBackupTemplate parses the template s for the log file filename rotated in mode,
+// whose backups may be compressed with the suffix zext.
+func parseBackupTemplate(s, filename string, mode _MODE_TIME, zext string) *backupTemplate {
+	bt := &backupTemplate{}
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		j := strings.IndexByte(s[i+1:], '}')
+		if i < 0 || j < 0 {
+			bt.addText(s)
+			break
+		}
+		j += i + 1
+		bt.addText(s[:i])
+		key, arg, _ := strings.Cut(s[i+1:j], ":")
+		switch key {
+		case "name":
+			bt.parts = append(bt.parts, templatePart{kind: _PART_NAME})
+		case "ext":
+			bt.parts = append(bt.parts, templatePart{kind: _PART_EXT})
+		case "date":
+			bt.parts = append(bt.parts, templatePart{kind: _PART_DATE, text: arg})
+		case "seq":
+			width, err := strconv.Atoi(arg)
+			if err != nil || width <= 0 {
+				width = _DEFAULT_SEQWIDTH
+			}
+			bt.parts = append(bt.parts, templatePart{kind: _PART_SEQ, width: width})
+		default:
+			bt.addText(s[i : j+1])
+		}
+		s = s[j+1:]
+	}
+	if !bt.has(_PART_SEQ) {
+		seq := []templatePart{{kind: _PART_TEXT, text: "."}, {kind: _PART_SEQ, width: _DEFAULT_SEQWIDTH}}
+		i := len(bt.parts)
+		for k, p := range bt.parts {
+			if p.kind == _PART_EXT {
+				i = k
+				break
+			}
+		}
+		bt.parts = append(bt.parts[:i], append(seq, bt.parts[i:]...)...)
+	}
+	bt.name, bt.mode, bt.zext = filename, mode, zext
+	bt.compile()
+	return bt
+}
+
+func (bt *backupTemplate) addText(s string) {
+	if s != "" {
+		bt.parts = append(bt.parts, templatePart{kind: _PART_TEXT, text: s})
+	}
+}
+
+func (bt *backupTemplate) has(kind int) bool {
+	for _, p := range bt.parts {
+		if p.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// compile builds the regular expression matching the names rendered for filename, with an optional compression suffix.
+// Digit and letter runs of date layouts are matched loosely, so that any layout is supported.
+func (bt *backupTemplate) compile() {
+	name, ext := splitExt(bt.name)
+	var sb strings.Builder
+	sb.WriteString("^")
+	group := 0
+	for _, p := range bt.parts {
+		switch p.kind {
+		case _PART_TEXT:
+			sb.WriteString(regexp.QuoteMeta(p.text))
+		case _PART_NAME:
+			sb.WriteString(regexp.QuoteMeta(name))
+		case _PART_EXT:
+			sb.WriteString(regexp.QuoteMeta(ext))
+		case _PART_DATE:
+			group++
+			if bt.date == 0 {
+				bt.date = group
+			}
+			sb.WriteString("(")
+			sb.WriteString(layoutPattern(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(bt.layout(p))))
+			sb.WriteString(")")
+		case _PART_SEQ:
+			group++
+			if bt.seq == 0 {
+				bt.seq = group
+			}
+			sb.WriteString(`(\d+)`)
+		}
+	}
+	sb.WriteString(zextPattern(bt.zext) + `$`)
+	bt.re = regexp.MustCompile(sb.String())
+}
+
+func layoutPattern(sample string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sample); {
+		j := i + 1
+		switch c := sample[i]; {
+		case c >= '0' && c <= '9':
+			for j < len(sample) && sample[j] >= '0' && sample[j] <= '9' {
+				j++
+			}
+			sb.WriteString(`\d+`)
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			for j < len(sample) && (sample[j] >= 'a' && sample[j] <= 'z' || sample[j] >= 'A' && sample[j] <= 'Z') {
+				j++
+			}
+			sb.WriteString(`[a-zA-Z]+`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(sample[i:j]))
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+func (bt *backupTemplate) layout(p templatePart) string {
+	if p.text != "" {
+		return p.text
+	}
+	return modeLayout(bt.mode, true)
+}
+
+func (bt *backupTemplate) render(tm time.Time, seq int) string {
+	name, ext := splitExt(bt.name)
+	var sb strings.Builder
+	for _, p := range bt.parts {
+		switch p.kind {
+		case _PART_TEXT:
+			sb.WriteString(p.text)
+		case _PART_NAME:
+			sb.WriteString(name)
+		case _PART_EXT:
+			sb.WriteString(ext)
+		case _PART_DATE:
+			sb.WriteString(tm.Format(bt.layout(p)))
+		case _PART_SEQ:
+			sb.WriteString(fmt.Sprintf("%0*d", p.width, seq))
+		}
+	}
+	return sb.String()
+}
+
+// templateBackupName returns the name in dir of the backup of period tm, whose sequence follows
+// the highest one of that period.
+func (t *fileHandler) templateBackupName(dir string, tm time.Time) string {
+	bt := t._template
+	date := ""
+	if m := bt.re.FindStringSubmatch(bt.render(tm, 0)); m != nil && bt.date > 0 {
+		date = m[bt.date]
+	}
+	seq := 0
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if m := bt.re.FindStringSubmatch(entry.Name()); m != nil && (bt.date == 0 || m[bt.date] == date) {
+				if i, err := strconv.Atoi(m[bt.seq]); err == nil && i > seq {
+					seq = i
+				}
+			}
+		}
+	}
+	for seq++; ; seq++ {
+		name := bt.render(tm, seq)
+		if !backupExist(filepath.Join(dir, name), bt.zext) {
+			return name
+		}
+	}
+}
+
+// key returns the period and sequence encoded in a backup name rendered by the template, for ordering.
+func (bt *backupTemplate) key(s string) []int64 {
+	m := bt.re.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	k := make([]int64, 0, 2)
+	if bt.date > 0 {
+		for _, p := range bt.parts {
+			if p.kind == _PART_DATE {
+				if tm, err := time.ParseInLocation(bt.layout(p), m[bt.date], time.Local); err == nil {
+					k = append(k, tm.Unix())
+				} else {
+					k = append(k, numericKey(m[bt.date])...)
+				}
+				break
+			}
+		}
+	}
+	seq, _ := strconv.ParseInt(m[bt.seq], 10, 64)
+	return append(k, seq)
+}
+
+// zext returns the suffix of compressed backups, empty without compression.
+func (t *fileHandler) zext() string {
+	if t._compressor != nil {
+		return t._compressor.Ext()
+	}
+	return ""
+}
+
+// _ZEXTS holds the suffixes of the built-in compressors. Backups bearing one of them are recognised
+// even after compression is turned off or another compressor is chosen.
+var _ZEXTS = []string{".gz", ".zz"}
+
+// zexts returns the suffixes a compressed backup may bear: zext and those of the built-in compressors.
+func zexts(zext string) []string {
+	for _, e := range _ZEXTS {
+		if e == zext {
+			return _ZEXTS
+		}
+	}
+	if zext == "" {
+		return _ZEXTS
+	}
+	return append([]string{zext}, _ZEXTS...)
+}
+
+// zextPattern returns the regular expression of the optional compression suffix of a backup name.
+func zextPattern(zext string) string {
+	var exts []string
+	for _, e := range zexts(zext) {
+		exts = append(exts, regexp.QuoteMeta(e))
+	}
+	return "(" + strings.Join(exts, "|") + ")?"
+}
+
+// trimZext removes the compression suffix, if any, from the backup name s.
+func trimZext(s, zext string) string {
+	for _, e := range zexts(zext) {
+		if strings.HasSuffix(s, e) {
+			return strings.TrimSuffix(s, e)
+		}
+	}
+	return s
+}
+
+// backupKey returns the ordering key of a backup name: for templates the period and sequence,
+// otherwise the numbers following the log name, e.g. [20240102 3] for app_20240102_3.log,
+// [2024 1 2 3] for app-2024-01-02.3.log of ROTATE_LINK. Names without a sequence sort before
+// the names of the same period that have one, as they are created first.
+func (t *fileHandler) backupKey(s string) []int64 {
+	if t._template != nil && t._rotate != ROTATE_LINK {
+		return t._template.key(s)
+	}
+	name, ext := splitExt(t._fileName)
+	s = trimZext(strings.TrimPrefix(s, name), t.zext())
+	s = strings.TrimSuffix(s, ext)
+	return numericKey(s)
+}
+
+// numericKey returns the runs of digits in s as numbers.
+func numericKey(s string) []int64 {
+	k := make([]int64, 0, 4)
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		n, _ := strconv.ParseInt(s[i:j], 10, 64)
+		k = append(k, n)
+		i = j
+	}
+	return k
+}
+
+func compareKey(a, b []int64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
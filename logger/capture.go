@@ -0,0 +1,130 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// consoleFile, when set, replaces os.Stdout as the console output,
+// so console echo keeps working while fd 1 is captured.
+var consoleFile atomic.Pointer[os.File]
+
+var (
+	captureMu sync.Mutex
+	capture   *stdCapture
+)
+
+type stdCapture struct {
+	origs map[int]*os.File
+	done  chan struct{}
+}
+
+// CaptureStdio redirects the stdout and stderr file descriptors of the process into the default logging instance.
+// See Logging.CaptureStdio.
+func CaptureStdio(stdoutLevel, stderrLevel LEVELTYPE) error {
+	return static_lo.CaptureStdio(stdoutLevel, stderrLevel)
+}
+
+// CaptureStdio redirects the stdout and stderr file descriptors (fd 1 and 2) of the process into this Logging instance,
+// so output printed directly to them, including by C libraries and child processes, is written to the log file.
+// Lines are logged at stdoutLevel or stderrLevel and prefixed with "[stdout] " or "[stderr] ". When Console is true,
+// the console echo still goes to the original stdout. Only one capture can be active per process; it is supported on Linux.
+//
+// CaptureStdio 将进程的标准输出与标准错误（fd 1、2）重定向到日志，直接写入fd的输出（包括C库与子进程）也会写入日志文件。
+// 每行分别以stdoutLevel、stderrLevel级别记录，并添加"[stdout] "、"[stderr] "前缀。Console为true时控制台输出仍写到原标准输出。
+// 同一进程只能有一个重定向，仅支持Linux。
+func (t *Logging) CaptureStdio(stdoutLevel, stderrLevel LEVELTYPE) (err error) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	if capture != nil {
+		return errors.New("stdio is already captured")
+	}
+	c := &stdCapture{origs: make(map[int]*os.File), done: make(chan struct{})}
+	var wg sync.WaitGroup
+	for _, s := range []struct {
+		fd     int
+		level  LEVELTYPE
+		prefix string
+	}{{1, stdoutLevel, "[stdout] "}, {2, stderrLevel, "[stderr] "}} {
+		var r *os.File
+		if r, err = c.redirect(s.fd); err != nil {
+			c.restore()
+			return
+		}
+		wg.Add(1)
+		go func(r *os.File, w *levelWriter) {
+			defer wg.Done()
+			defer r.Close()
+			io.Copy(w, r)
+			w.Close()
+		}(r, &levelWriter{logging: t, level: s.level, prefix: s.prefix})
+	}
+	consoleFile.Store(c.origs[1])
+	go func() {
+		wg.Wait()
+		close(c.done)
+	}()
+	capture = c
+	return
+}
+
+// ReleaseStdio restores the stdout and stderr file descriptors captured by CaptureStdio.
+// It waits up to one second for the pending output to be logged.
+//
+// 恢复被CaptureStdio重定向的标准输出与标准错误
+func ReleaseStdio() (err error) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	if capture == nil {
+		return
+	}
+	err = capture.restore()
+	consoleFile.Store(nil)
+	select {
+	case <-capture.done:
+	case <-time.After(time.Second):
+	}
+	capture = nil
+	return
+}
+
+// redirect points fd at the write end of a new pipe and returns its read end.
+func (c *stdCapture) redirect(fd int) (r *os.File, err error) {
+	var orig, w *os.File
+	if orig, err = dupFd(fd); err != nil {
+		return
+	}
+	if r, w, err = os.Pipe(); err != nil {
+		orig.Close()
+		return
+	}
+	defer w.Close()
+	if err = dupTo(w, fd); err != nil {
+		orig.Close()
+		r.Close()
+		return nil, err
+	}
+	c.origs[fd] = orig
+	return
+}
+
+func (c *stdCapture) restore() (err error) {
+	for fd, orig := range c.origs {
+		if e := dupTo(orig, fd); e != nil {
+			err = e
+		}
+		orig.Close()
+	}
+	return
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build linux
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+func dupFd(fd int) (*os.File, error) {
+	nfd, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(nfd)
+	return os.NewFile(uintptr(nfd), "/dev/fd/"+string(itoa(fd, -1))), nil
+}
+
+func dupTo(f *os.File, fd int) error {
+	return syscall.Dup3(int(f.Fd()), fd, 0)
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build !linux
+
+package logger
+
+import (
+	"errors"
+	"os"
+)
+
+var errCaptureUnsupported = errors.New("capturing stdio is only supported on linux")
+
+func dupFd(fd int) (*os.File, error) {
+	return nil, errCaptureUnsupported
+}
+
+func dupTo(f *os.File, fd int) error {
+	return errCaptureUnsupported
+}
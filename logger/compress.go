@@ -0,0 +1,119 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Compressor compresses backup files. Besides GzipCompressor and ZlibCompressor, any codec can be plugged in
+// by implementing it. Implementations must be safe for concurrent use.
+//
+// Compressor 备份文件的压缩方式，内置GzipCompressor与ZlibCompressor，也可以自行实现以接入其他压缩算法，实现需并发安全。
+type Compressor interface {
+	// Ext returns the suffix appended to the names of compressed files, e.g. ".gz".
+	Ext() string
+	// NewWriter returns a writer compressing to w. name is the base name of the file being compressed.
+	NewWriter(w io.Writer, name string) (io.WriteCloser, error)
+}
+
+// GzipCompressor compresses backups with gzip. Level is one of the compress/gzip levels, 0 means gzip.DefaultCompression.
+// GzipCompressor 以gzip压缩备份文件，Level为compress/gzip的压缩级别，0表示默认级别
+type GzipCompressor struct {
+	Level int
+}
+
+func (c *GzipCompressor) Ext() string {
+	return ".gz"
+}
+
+func (c *GzipCompressor) NewWriter(w io.Writer, name string) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	gw, err := gzip.NewWriterLevel(w, level)
+	if err == nil {
+		gw.Header.Name = name
+	}
+	return gw, err
+}
+
+// ZlibCompressor compresses backups with zlib. Level is one of the compress/zlib levels, 0 means zlib.DefaultCompression.
+// ZlibCompressor 以zlib压缩备份文件，Level为compress/zlib的压缩级别，0表示默认级别
+type ZlibCompressor struct {
+	Level int
+}
+
+func (c *ZlibCompressor) Ext() string {
+	return ".zz"
+}
+
+func (c *ZlibCompressor) NewWriter(w io.Writer, name string) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = zlib.DefaultCompression
+	}
+	return zlib.NewWriterLevel(w, level)
+}
+
+// compressSem bounds the number of backups compressed at the same time across all Logging instances.
+var compressSem = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+// _TMPEXT is the suffix of a compressed file being written; it is renamed once complete.
+const _TMPEXT = ".tmp"
+
+// compressFile streams src into src+c.Ext() and removes src once the compressed file is complete.
+// The compressed file is written under a temporary name first, so that it never exists half-written.
+func compressFile(c Compressor, src string) (err error) {
+	var f1, f2 *os.File
+	if f1, err = os.Open(src); err != nil {
+		return
+	}
+	defer f1.Close()
+	dst := src + c.Ext()
+	tmp := dst + _TMPEXT
+	if f2, err = os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666); err != nil {
+		return
+	}
+	var w io.WriteCloser
+	if w, err = c.NewWriter(f2, filepath.Base(src)); err == nil {
+		if _, err = io.Copy(w, f1); err == nil {
+			if err = w.Close(); err == nil {
+				err = f2.Sync()
+			}
+		}
+	}
+	if e := f2.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	f1.Close()
+	return os.Remove(src)
+}
+
+// background runs fn on a goroutine tracked by Close.
+func (t *Logging) background(fn func()) {
+	t.bgwg.Add(1)
+	go func() {
+		defer t.bgwg.Done()
+		defer recoverable(nil)
+		fn()
+	}()
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/donnie4w/gofer/buffer"
+)
+
+// Field is a named value attached to a log entry.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields is an ordered list of fields. It is encoded to JSON as an object.
+type Fields []Field
+
+// Get returns the value of the field named key.
+func (f Fields) Get(key string) (any, bool) {
+	for _, v := range f {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (f Fields) MarshalJSON() ([]byte, error) {
+	bs := []byte{'{'}
+	for i, v := range f {
+		if i > 0 {
+			bs = append(bs, ',')
+		}
+		bs = strconv.AppendQuote(bs, v.Key)
+		bs = append(bs, ':')
+		vs, err := json.Marshal(v.Value)
+		if err != nil {
+			vs = strconv.AppendQuote(nil, fmt.Sprint(v.Value))
+		}
+		bs = append(bs, vs...)
+	}
+	return append(bs, '}'), nil
+}
+
+// ContextExtractor pulls a value out of a context. It returns false if the context does not carry the value.
+//
+// ContextExtractor 从context中提取值，context中不存在该值时返回false
+type ContextExtractor func(ctx context.Context) (any, bool)
+
+type contextExtractor struct {
+	name string
+	fn   ContextExtractor
+}
+
+var (
+	extractorMu       sync.Mutex
+	contextExtractors atomic.Pointer[[]contextExtractor]
+)
+
+// RegisterContextExtractor registers an extractor whose value is attached to every entry logged by the *Context
+// methods as the field name. Fields are appended to the message as name=value, or placed by the formatter with
+// {field:name}. Registering an existing name replaces its extractor; a nil fn removes it.
+//
+// 注册context提取函数，通过*Context方法打印日志时，提取的值以name为字段名附加到日志中。
+// 字段以name=value形式追加到日志内容后，或通过格式化字符串中的{field:name}指定位置。
+//
+// e.g.
+//
+//	logger.RegisterContextExtractor("request_id", func(ctx context.Context) (any, bool) {
+//		v, ok := ctx.Value(requestIDKey{}).(string)
+//		return v, ok
+//	})
+//	logger.InfoContext(ctx, "this is a info message")
+func RegisterContextExtractor(name string, fn ContextExtractor) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	var extractors []contextExtractor
+	if p := contextExtractors.Load(); p != nil {
+		for _, e := range *p {
+			if e.name != name {
+				extractors = append(extractors, e)
+			}
+		}
+	}
+	if fn != nil {
+		extractors = append(extractors, contextExtractor{name: name, fn: fn})
+	}
+	contextExtractors.Store(&extractors)
+}
+
+func contextFields(ctx context.Context) (fields []Field) {
+	if ctx == nil {
+		return
+	}
+	if p := contextExtractors.Load(); p != nil {
+		for _, e := range *p {
+			if v, ok := e.fn(ctx); ok {
+				fields = append(fields, Field{Key: e.name, Value: v})
+			}
+		}
+	}
+	return
+}
+
+func appendFields(bs []byte, fields []Field) []byte {
+	for _, f := range fields {
+		bs = append(bs, ' ')
+		bs = append(bs, f.Key...)
+		bs = append(bs, '=')
+		bs = appendValue(bs, f.Value)
+	}
+	return bs
+}
+
+func appendFieldValue(buf *buffer.Buffer, fields []Field, key string) {
+	if v, ok := Fields(fields).Get(key); ok {
+		buf.Write(appendValue(nil, v))
+	}
+}
+
+func appendValue(bs []byte, v any) []byte {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.AppendQuote(bs, s)
+	}
+	return append(bs, s...)
+}
+
+// DebugContext logs a message at the DEBUG level using the default logging instance,
+// attaching the values of the registered context extractors.
+func DebugContext(ctx context.Context, v ...any) *Logging {
+	return static_lo.printlnctx(ctx, nil, LEVEL_DEBUG, 2, v...)
+}
+
+// InfoContext logs a message at the INFO level using the default logging instance,
+// attaching the values of the registered context extractors.
+func InfoContext(ctx context.Context, v ...any) *Logging {
+	return static_lo.printlnctx(ctx, nil, LEVEL_INFO, 2, v...)
+}
+
+// WarnContext logs a message at the WARN level using the default logging instance,
+// attaching the values of the registered context extractors.
+func WarnContext(ctx context.Context, v ...any) *Logging {
+	return static_lo.printlnctx(ctx, nil, LEVEL_WARN, 2, v...)
+}
+
+// ErrorContext logs a message at the ERROR level using the default logging instance,
+// attaching the values of the registered context extractors.
+func ErrorContext(ctx context.Context, v ...any) *Logging {
+	return static_lo.printlnctx(ctx, nil, LEVEL_ERROR, 2, v...)
+}
+
+// FatalContext logs a message at the FATAL level using the default logging instance,
+// attaching the values of the registered context extractors.
+func FatalContext(ctx context.Context, v ...any) *Logging {
+	return static_lo.printlnctx(ctx, nil, LEVEL_FATAL, 2, v...)
+}
+
+// DebugContext logs a message at the DEBUG level, attaching the values of the registered context extractors.
+func (t *Logging) DebugContext(ctx context.Context, v ...any) *Logging {
+	return t.printlnctx(ctx, nil, LEVEL_DEBUG, 2, v...)
+}
+
+// InfoContext logs a message at the INFO level, attaching the values of the registered context extractors.
+func (t *Logging) InfoContext(ctx context.Context, v ...any) *Logging {
+	return t.printlnctx(ctx, nil, LEVEL_INFO, 2, v...)
+}
+
+// WarnContext logs a message at the WARN level, attaching the values of the registered context extractors.
+func (t *Logging) WarnContext(ctx context.Context, v ...any) *Logging {
+	return t.printlnctx(ctx, nil, LEVEL_WARN, 2, v...)
+}
+
+// ErrorContext logs a message at the ERROR level, attaching the values of the registered context extractors.
+func (t *Logging) ErrorContext(ctx context.Context, v ...any) *Logging {
+	return t.printlnctx(ctx, nil, LEVEL_ERROR, 2, v...)
+}
+
+// FatalContext logs a message at the FATAL level, attaching the values of the registered context extractors.
+func (t *Logging) FatalContext(ctx context.Context, v ...any) *Logging {
+	return t.printlnctx(ctx, nil, LEVEL_FATAL, 2, v...)
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build go1.23
+
+package logger
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// setCrashOutput makes the runtime write fatal crash reports, such as unrecovered panics, to f as well as stderr.
+// A nil f disables it.
+func setCrashOutput(f *os.File) error {
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
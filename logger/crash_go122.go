@@ -0,0 +1,23 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build !go1.23
+
+package logger
+
+import (
+	"errors"
+	"os"
+)
+
+// setCrashOutput requires runtime/debug.SetCrashOutput, available since go1.23.
+func setCrashOutput(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	return errors.New("crash output requires go1.23 or later")
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{0, 59, nil}
+	cronHour   = cronField{0, 23, nil}
+	cronDom    = cronField{1, 31, nil}
+	cronMonth  = cronField{1, 12, map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}}
+	cronDow    = cronField{0, 7, map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a cron expression such as "0 2 * * *" or "*/30 8-18 * * MON-FRI". Each field accepts
+// "*", values, ranges "a-b", steps "*/n" or "a-b/n" and comma separated lists; months and weekdays also
+// accept three-letter names. The descriptors @yearly, @monthly, @weekly, @daily and @hourly are supported.
+// A time skipped by a daylight saving change does not fire on that day.
+func parseCron(expr string) (c *cronSchedule, err error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("cron expression needs 5 fields: " + expr)
+	}
+	c = &cronSchedule{domStar: strings.HasPrefix(fields[2], "*"), dowStar: strings.HasPrefix(fields[4], "*")}
+	for i, p := range []struct {
+		bits *uint64
+		f    cronField
+	}{{&c.minute, cronMinute}, {&c.hour, cronHour}, {&c.dom, cronDom}, {&c.month, cronMonth}, {&c.dow, cronDow}} {
+		if *p.bits, err = parseCronField(fields[i], p.f); err != nil {
+			return nil, err
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	if c.next(time.Now()).IsZero() {
+		return nil, errors.New("cron expression never fires: " + expr)
+	}
+	return
+}
+
+func parseCronField(s string, f cronField) (bits uint64, err error) {
+	for _, part := range strings.Split(s, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, errors.New("invalid cron step: " + part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			if lo, err = f.value(a); err != nil {
+				return
+			}
+			if isRange {
+				if hi, err = f.value(b); err != nil {
+					return
+				}
+			} else if step == 1 {
+				hi = lo
+			}
+			if lo > hi {
+				return 0, errors.New("invalid cron range: " + part)
+			}
+		}
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New("invalid cron value: " + s)
+	}
+	return v, nil
+}
+
+func (c *cronSchedule) dayMatches(tm time.Time) bool {
+	dom, dow := c.dom&(1<<uint(tm.Day())) != 0, c.dow&(1<<uint(tm.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// _CRON_MAXSTEPS bounds the steps of a search for the next or previous firing, far above the few thousand
+// that five years of months, days, hours and minutes can take.
+const _CRON_MAXSTEPS = 1 << 16
+
+// next returns the first time the schedule fires after tm, or the zero time if it does not within five years.
+// Every step moves forward in absolute time: a wall clock time inside a daylight saving gap resolves to an
+// earlier instant, and is then replaced by the next minute.
+func (c *cronSchedule) next(tm time.Time) time.Time {
+	loc := tm.Location()
+	t := tm.Truncate(time.Minute).Add(time.Minute)
+	for i, limit := 0, tm.Year()+5; i < _CRON_MAXSTEPS && t.Year() <= limit; i++ {
+		var n time.Time
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			n = startOfDay(t.Year(), t.Month()+1, 1, loc)
+		case !c.dayMatches(t):
+			n = startOfDay(t.Year(), t.Month(), t.Day()+1, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			n = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			n = t.Add(time.Minute)
+		default:
+			return t
+		}
+		if !n.After(t) {
+			n = t.Add(time.Minute)
+		}
+		t = n
+	}
+	return time.Time{}
+}
+
+// prev returns the last time the schedule fired at or before tm, or tm itself if it did not within five years.
+// Every step moves backward in absolute time, as in next.
+func (c *cronSchedule) prev(tm time.Time) time.Time {
+	loc := tm.Location()
+	t := tm.Truncate(time.Minute)
+	for i, limit := 0, tm.Year()-5; i < _CRON_MAXSTEPS && t.Year() >= limit; i++ {
+		var p time.Time
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			p = startOfDay(t.Year(), t.Month(), 1, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			p = startOfDay(t.Year(), t.Month(), t.Day(), loc).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			p = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			p = t.Add(-time.Minute)
+		default:
+			return t
+		}
+		if !p.Before(t) {
+			p = t.Add(-time.Minute)
+		}
+		t = p
+	}
+	return tm
+}
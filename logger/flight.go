@@ -0,0 +1,56 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+const default_flightmark = "[FLIGHT]"
+
+// setFlightRecorder enables the flight recorder, which keeps the latest size records below the
+// configured level in memory and writes them out, prefixed with mark, when an ERROR or FATAL record is logged.
+func (t *Logging) setFlightRecorder(size int, mark string) {
+	if size <= 0 {
+		t.flightRecorder, t.flightMark = nil, nil
+		return
+	}
+	if mark == "" {
+		mark = default_flightmark
+	}
+	t.flightRecorder, t.flightMark = NewRingBuffer(size, 0), []byte(mark)
+}
+
+func (t *Logging) recordFlight(fields []Field, format *string, _level LEVELTYPE, calldepth int, v ...any) {
+	if t.callDepth > 0 {
+		calldepth += t.callDepth
+	}
+	msg, bs, buf := t.formatLine(fields, format, _level, k1(calldepth), v...)
+	if buf != nil {
+		defer buf.Free()
+	}
+	rec := newRecord(_level, k1(calldepth), msg, bs)
+	rec.Fields = fields
+	t.flightRecorder.WriteRecord(rec)
+}
+
+// flushFlight writes out the buffered records one by one, the same way as the records being logged: to the sinks,
+// the log file and the console, each line prefixed with the flight mark.
+func (t *Logging) flushFlight() {
+	sinks := t.sinks.Load()
+	for _, r := range t.flightRecorder.drain() {
+		r.Raw = append(append(make([]byte, 0, len(t.flightMark)+len(r.Raw)), t.flightMark...), r.Raw...)
+		if sinks != nil {
+			for _, s := range *sinks {
+				s.WriteRecord(r)
+			}
+		}
+		if t._isFileWell {
+			t.writeLine(r.Raw)
+		}
+		if t._isConsole {
+			consolewriter(r.Raw, false)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// _GZIP_EXT is the suffix of the backups of a gzip active file, and of the file itself in ROTATE_LINK mode,
+// unless the name ends with it already.
+const _GZIP_EXT = ".gz"
+
+// errNotGzip reports a log file holding data but no gzip member, which cannot be continued as a gzip stream.
+var errNotGzip = errors.New("the log file is not a gzip stream")
+
+// _GZIP_MEMBER_INTERVAL is how long a gzip member of the active file stays open. The records of
+// an open member are lost in a crash, the members before it stay readable.
+const _GZIP_MEMBER_INTERVAL = time.Second
+
+// gzipFile writes the active log file as a series of gzip members. A member is started by the first
+// write after the previous one ended, and ended _GZIP_MEMBER_INTERVAL later or at Close. Readers such
+// as gzip -d and compress/gzip read the concatenated members as one stream.
+type gzipFile struct {
+	mu     sync.Mutex
+	f      *os.File
+	bw     *bufio.Writer
+	zw     *gzip.Writer
+	timer  *time.Timer
+	open   bool // a member is being written
+	closed bool
+	size   atomic.Int64 // compressed bytes of the file, including those not flushed yet
+	raw    int64        // uncompressed bytes of the members found when the file was opened
+	lines  int64        // lines of the members found when the file was opened
+}
+
+// openGzipFile prepares the log file f for appending gzip members. The members already in it are read to
+// learn their sizes, and an incomplete member left by a crash is cut off, as no member after it could be read.
+// A file with data but no complete member, such as a plain text log, is left untouched and errNotGzip returned.
+func openGzipFile(f *os.File) (*gzipFile, error) {
+	g := &gzipFile{f: f}
+	end, members := g.scan()
+	if fs, err := f.Stat(); err != nil {
+		return nil, err
+	} else if fs.Size() > end {
+		if members == 0 {
+			return nil, errNotGzip
+		}
+		if err = f.Truncate(end); err != nil {
+			return nil, err
+		}
+	}
+	g.size.Store(end)
+	g.bw = bufio.NewWriterSize(f, 32<<10)
+	g.zw = gzip.NewWriter(countWriter{g.bw, &g.size})
+	return g, nil
+}
+
+// rotatePlain keeps a log file that is not a gzip stream, e.g. one written before Gzipactive was set, as a
+// backup without the gzip suffix, and opens a new active file in its place.
+func (t *fileHandler) rotatePlain() (err error) {
+	t._gzip.Store(nil)
+	t.file.Close()
+	if t._rotate == ROTATE_LINK {
+		t._fresh = true
+	} else {
+		var bak string
+		if bak, err = t.rename(); err != nil {
+			return
+		} else if bak == "" {
+			return errNotGzip
+		}
+	}
+	return t.openFileHandler()
+}
+
+// scan reads the complete members of the file and returns their number and where the last one ends.
+func (g *gzipFile) scan() (end int64, members int) {
+	f, err := os.Open(g.f.Name())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	cr := &countReader{r: f}
+	br := bufio.NewReader(cr)
+	var zr gzip.Reader
+	buf := make([]byte, 32<<10)
+	for {
+		if _, err := br.Peek(1); err != nil || zr.Reset(br) != nil {
+			return
+		}
+		zr.Multistream(false)
+		var raw, lines int64
+		for {
+			n, err := zr.Read(buf)
+			raw, lines = raw+int64(n), lines+int64(bytes.Count(buf[:n], []byte{'\n'}))
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return
+			}
+		}
+		end, members = cr.n-int64(br.Buffered()), members+1
+		g.raw, g.lines = g.raw+raw, g.lines+lines
+	}
+}
+
+func (g *gzipFile) Write(bs []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return 0, os.ErrClosed
+	}
+	if !g.open {
+		g.zw.Reset(countWriter{g.bw, &g.size})
+		g.open = true
+		g.timer = time.AfterFunc(_GZIP_MEMBER_INTERVAL, g.endMember)
+	}
+	return g.zw.Write(bs)
+}
+
+func (g *gzipFile) endMember() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.closeMember(); err != nil {
+		fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
+	}
+}
+
+// closeMember writes the trailer of the open member and flushes it to the file.
+func (g *gzipFile) closeMember() (err error) {
+	if g.open {
+		g.open = false
+		g.timer.Stop()
+		if err = g.zw.Close(); err == nil {
+			err = g.bw.Flush()
+		}
+	}
+	return
+}
+
+// Close ends the open member and closes the file.
+func (g *gzipFile) Close() (err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.closed = true
+	err = g.closeMember()
+	if e := g.f.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+type countWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c countWriter) Write(bs []byte) (n int, err error) {
+	n, err = c.w.Write(bs)
+	c.n.Add(int64(n))
+	return
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(bs []byte) (n int, err error) {
+	n, err = c.r.Read(bs)
+	c.n += int64(n)
+	return
+}
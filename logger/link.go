@@ -0,0 +1,131 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	_LINKFORMAT_DAY   = "2006-01-02"
+	_LINKFORMAT_HOUR  = "2006-01-02T15"
+	_LINKFORMAT_MONTH = "2006-01"
+)
+
+// linkPeriod returns the period part of active file names in ROTATE_LINK mode.
+// Size-only rotation uses the day, so that names stay unique across days.
+func (t *fileHandler) linkPeriod(now time.Time) string {
+	if t._cutmode&_TIMEMODE == _TIMEMODE {
+		return t.periodStart(now).Format(modeLayout(t._mode, true))
+	}
+	return now.Format(_LINKFORMAT_DAY)
+}
+
+func splitExt(filename string) (name, ext string) {
+	index := strings.LastIndex(filename, ".")
+	if index <= 0 {
+		index = len(filename)
+	}
+	return filename[:index], filename[index:]
+}
+
+// linkActiveName returns the name of the file to write to in ROTATE_LINK mode: name-period.ext, or name-period.seq.ext
+// with the sequence after the highest one of the period, followed by the gzip suffix for a gzip active file. The file the link points at is reused
+// if it belongs to the current period and is not full.
+func (t *fileHandler) linkActiveName() string {
+	name, ext := splitExt(t._fileName)
+	if t._gzipactive && ext != _GZIP_EXT {
+		ext += _GZIP_EXT
+	}
+	prefix := name + "-" + t.linkPeriod(loctime())
+	if target, err := os.Readlink(filepath.Join(t._fileDir, t.linkName())); err == nil && !t._fresh {
+		target = filepath.Base(target)
+		if regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `(\.\d+)?` + regexp.QuoteMeta(ext) + `$`).MatchString(target) {
+			if fi, err := os.Stat(filepath.Join(t._fileDir, target)); err == nil && (t._cutmode&_SIZEMODE == 0 || fi.Size() < t._maxSize*int64(t._unit)) &&
+				(t._cutmode&_COUNTMODE == 0 || countLines(filepath.Join(t._fileDir, target)) < t._maxrecords) {
+				return target
+			}
+		}
+	}
+	list, _ := _getDirList(t._fileDir)
+	seq, found := lastSeq(list, regexp.MustCompile(`^`+regexp.QuoteMeta(prefix)+`(?:\.(\d+))?`+regexp.QuoteMeta(ext)+zextPattern(t.zext())+`$`))
+	i := 0
+	if found {
+		i = seq + 1
+	}
+	for ; ; i++ {
+		fname := prefix + ext
+		if i > 0 {
+			fname = fmt.Sprint(prefix, ".", i, ext)
+		}
+		if !backupExist(filepath.Join(t._fileDir, fname), t.zext()) {
+			return fname
+		}
+	}
+}
+
+// activeName returns the name of the file written to in ROTATE_LINK mode. It is set before the file is
+// created, so that a backup listing never takes the file being written for a backup.
+func (t *fileHandler) activeName() string {
+	if p := t._activeName.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// path returns the path that refers to the open log file: the symlink in ROTATE_LINK mode, the log file otherwise.
+func (t *fileHandler) path() string {
+	if t._rotate == ROTATE_LINK {
+		return filepath.Join(t._fileDir, t.linkName())
+	}
+	return filepath.Join(t._fileDir, t._fileName)
+}
+
+func (t *fileHandler) linkName() string {
+	if t._linkname != "" {
+		return t._linkname
+	}
+	return t._fileName
+}
+
+// linkPattern matches the active and backup file names of ROTATE_LINK mode.
+func (t *fileHandler) linkPattern() string {
+	name, ext := splitExt(t._fileName)
+	return `^` + regexp.QuoteMeta(name) + `-\d{4}-\d{2}(-\d{2}(T\d{2}(\d{2})?)?)?(\.\d+)?` + regexp.QuoteMeta(ext) + zextPattern(t.zext()) + `$`
+}
+
+// updateLink atomically points the link at the active file. A regular file left at the link path,
+// e.g. by an earlier ROTATE_RENAME configuration, is kept as a backup first.
+func (t *fileHandler) updateLink() (err error) {
+	link := filepath.Join(t._fileDir, t.linkName())
+	if fi, e := os.Lstat(link); e == nil && fi.Mode().IsRegular() {
+		name, ext := splitExt(t._fileName)
+		for i := 1; ; i++ {
+			bak := filepath.Join(t._fileDir, fmt.Sprint(name, "-", t.linkPeriod(fi.ModTime()), ".", i, ext))
+			if !backupExist(bak, t.zext()) {
+				if err = os.Rename(link, bak); err != nil {
+					return
+				}
+				break
+			}
+		}
+	}
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err = os.Symlink(t.activeName(), tmp); err == nil {
+		if err = os.Rename(tmp, link); err != nil {
+			os.Remove(tmp)
+		}
+	}
+	return
+}
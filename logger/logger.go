@@ -9,15 +9,17 @@ package logger
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -30,9 +32,10 @@ import (
 
 type LEVELTYPE int8
 type _UNIT int64
-type _MODE_TIME uint8
+type _MODE_TIME uint32
 type _CUTMODE int //dailyRolling ,rollingFile
 type _FORMAT int
+type _ROTATE int8
 
 const (
 	_DATEFORMAT_DAY   = "20060102"
@@ -58,6 +61,27 @@ const (
 	MODE_HOUR  _MODE_TIME = 1
 	MODE_DAY   _MODE_TIME = 2
 	MODE_MONTH _MODE_TIME = 3
+	MODE_WEEK  _MODE_TIME = 4 // weeks starting on Monday, see MODE_WEEK_FROM for another weekday
+)
+
+const (
+	// ROTATE_RENAME
+	//
+	// the active file is renamed to the backup name and a new file is created
+	// 活动日志文件重命名为备份文件，并创建新的日志文件
+	ROTATE_RENAME _ROTATE = 0
+
+	// ROTATE_COPYTRUNCATE
+	//
+	// the active file is copied to the backup name and then truncated in place, so that programs holding the path open keep following it
+	// 活动日志文件复制为备份文件后原地截断，外部按路径打开该文件的程序可继续跟随
+	ROTATE_COPYTRUNCATE _ROTATE = 1
+
+	// ROTATE_LINK
+	//
+	// the active file is named after its period, e.g. app-2024-01-01.log, and a symlink (the file name, or LinkName) points at it; backups are never renamed
+	// 活动日志文件以时间段命名，如app-2024-01-01.log，并由符号链接（文件名或LinkName）指向它，备份文件无需重命名
+	ROTATE_LINK _ROTATE = 2
 )
 
 const (
@@ -153,6 +177,7 @@ const (
 	_TIMEMODE  _CUTMODE = 1
 	_SIZEMODE  _CUTMODE = 2
 	_MIXEDMODE _CUTMODE = 3
+	_COUNTMODE _CUTMODE = 4
 )
 
 // 使用常量定义标志位组合
@@ -428,37 +453,60 @@ func getlevelname(level LEVELTYPE) (levelname []byte) {
 
 // Logging is the primary data structure for configuring and managing logging behavior.
 type Logging struct {
-	_level        LEVELTYPE                 // Log level, e.g., DEBUG, INFO, WARN, ERROR, etc.
-	_format       _FORMAT                   // Log format.
-	_rwLock       *sync.RWMutex             // Read-write lock for concurrent safe access to the logging struct.
-	_fileDir      string                    // Directory path where log files are stored.
-	_fileName     string                    // Base name of the log file.
-	_maxSize      int64                     // Maximum size of a single log file.
-	_unit         _UNIT                     // Size unit, e.g., Byte, KB, MB, etc.
-	_cutmode      _CUTMODE                  // Log file cutting mode, e.g., by size or by time.
-	_mode         _MODE_TIME                // Time-based rolling mode for log files, e.g., daily, weekly, etc.
-	_filehandler  *fileHandler              // File handler for operations on log files.
-	_isFileWell   bool                      // Indicates whether the log file is in good condition.
-	_formatter    string                    // Formatting string for customizing the log output format.
-	_maxBackup    int                       // Maximum number of backup log files to keep.
-	_isConsole    bool                      // Whether to also output logs to the console.
-	_gzip         bool                      // Whether to enable GZIP compression for old log files.
-	prevTime      int64                     // The timestamp of last print
-	callDepth     int                       // the depth of function call
-	stacktrace    LEVELTYPE                 // Log level, e.g., DEBUG, INFO, WARN, ERROR, etc.
-	customHandler func(lc *LogContext) bool // Custom log handler function allowing users to define additional log processing logic.
-	atStart       atomic.Int32
-	atStop        atomic.Int32
-	leveloption   [5]*LevelOption
-	attrFormat    *AttrFormat
-	tmTimer       *time.Timer
-	err           error
+	_level            atomic.Int32              // Log level, e.g., DEBUG, INFO, WARN, ERROR, etc.
+	_format           _FORMAT                   // Log format.
+	_rwLock           *sync.RWMutex             // Read-write lock for concurrent safe access to the logging struct.
+	_fileDir          string                    // Directory path where log files are stored.
+	_fileName         string                    // Base name of the log file.
+	_maxSize          int64                     // Maximum size of a single log file.
+	_unit             _UNIT                     // Size unit, e.g., Byte, KB, MB, etc.
+	_cutmode          _CUTMODE                  // Log file cutting mode, e.g., by size or by time.
+	_mode             _MODE_TIME                // Time-based rolling mode for log files, e.g., daily, weekly, etc.
+	_timeOffset       time.Duration             // Shift of the time-based rotation boundaries.
+	_cron             *cronSchedule             // Rotation schedule when a cron expression is configured.
+	_filehandler      *fileHandler              // File handler for operations on log files.
+	_isFileWell       bool                      // Indicates whether the log file is in good condition.
+	_formatter        string                    // Formatting string for customizing the log output format.
+	_maxBackup        int                       // Maximum number of backup log files to keep.
+	_maxAge           int                       // Maximum age of backup log files in days.
+	_maxTotalSize     int64                     // Maximum combined size of backup log files.
+	_maxRecords       int64                     // Maximum number of records in a log file in record-count mode.
+	_isConsole        bool                      // Whether to also output logs to the console.
+	_compressor       Compressor                // Compression of backup log files, nil for none.
+	_keepUncompressed int                       // Number of the newest backups left uncompressed.
+	_gzipActive       bool                      // Whether the active file is written as a gzip stream.
+	_sizeCompressed   bool                      // Whether the maximum size counts the compressed bytes of a gzip active file.
+	_rotate           _ROTATE                   // How the active file is turned into a backup.
+	_linkname         string                    // Name of the symlink to the active file in ROTATE_LINK mode.
+	_backupname       string                    // Backup file name template, empty for the built-in names.
+	_archiveDir       string                    // Directory the backups are moved to, empty for the log directory.
+	_archiveDated     bool                      // Whether backups are archived into YYYY/MM/DD subfolders.
+	prevTime          int64                     // The timestamp of last print
+	callDepth         int                       // the depth of function call
+	stacktrace        LEVELTYPE                 // Log level, e.g., DEBUG, INFO, WARN, ERROR, etc.
+	customHandler     func(lc *LogContext) bool // Custom log handler function allowing users to define additional log processing logic.
+	atStart           atomic.Int32
+	atStop            atomic.Int32
+	leveloption       [5]*LevelOption
+	attrFormat        *AttrFormat
+	tmTimer           *time.Timer
+	sinks             atomic.Pointer[[]Sink]
+	flightRecorder    *RingBuffer
+	flightMark        []byte
+	crashOutput       bool
+	onFileEvent       func(ev *FileEvent)
+	watchStop         chan struct{}
+	bgwg              sync.WaitGroup        // background compression and retention, waited for by Close
+	bgmu              sync.Mutex            // serializes the cleanups of backups
+	err               atomic.Pointer[error] // the error that stops the logging, read on every log call
+	openErr           *error                // the failure to open the log file recorded in err, if any
 }
 
 // NewLogger creates and returns a new instance of the Logging struct.
 // This function initializes a Logging object with default values or specific configurations as needed.
 func NewLogger() (log *Logging) {
-	log = &Logging{_level: default_level, _cutmode: _TIMEMODE, _rwLock: new(sync.RWMutex), _format: default_format, _isConsole: true}
+	log = &Logging{_cutmode: _TIMEMODE, _rwLock: new(sync.RWMutex), _format: default_format, _isConsole: true}
+	log._level.Store(int32(default_level))
 	log.newfileHandler()
 	return
 }
@@ -609,12 +657,12 @@ func (t *Logging) WriteBin(bs []byte) (bakfn string, err error) {
 	if t._isFileWell {
 		var openFileErr error
 		if t._filehandler.mustBackUp(len(bs)) {
-			bakfn, err, openFileErr = t.backUp()
+			if bakfn, err, openFileErr = t.backUp(); bakfn != "" {
+				bakfn = filepath.Base(bakfn)
+			}
 		}
 		if openFileErr == nil {
-			t._rwLock.RLock()
-			_, err = t._filehandler.write(bs)
-			t._rwLock.RUnlock()
+			_, err = t.writeFile(bs)
 		}
 	} else {
 		err = errors.New("no log file found")
@@ -628,9 +676,7 @@ func (t *Logging) Write(bs []byte) (n int, err error) {
 			_, err, openFileErr = t.backUp()
 		}
 		if openFileErr == nil {
-			t._rwLock.RLock()
-			n, err = t._filehandler.write(bs)
-			t._rwLock.RUnlock()
+			n, err = t.writeFile(bs)
 		}
 	} else {
 		err = errors.New("no log file found")
@@ -638,6 +684,30 @@ func (t *Logging) Write(bs []byte) (n int, err error) {
 	return
 }
 
+// writeFile writes bs to the log file under the read lock. In record-count mode bs takes a slot of the
+// current file first; when none is left, the file is rotated under the write lock and bs goes to the new one,
+// so that no file gets more than the maximum number of records however many goroutines are writing.
+func (t *Logging) writeFile(bs []byte) (n int, err error) {
+	t._rwLock.RLock()
+	if t._filehandler.reserveRecords(bs) {
+		n, err = t._filehandler.write(bs)
+		t._rwLock.RUnlock()
+		return
+	}
+	t._rwLock.RUnlock()
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	if !t._filehandler.reserveRecords(bs) {
+		t._filehandler._fresh = true
+		if _, err, openFileErr := t.rotateFile(); openFileErr != nil {
+			return 0, openFileErr
+		} else if !t._filehandler.reserveRecords(bs) {
+			return 0, err
+		}
+	}
+	return t._filehandler.write(bs)
+}
+
 // SetFormat sets the logging format to the specified format type.
 //
 // Parameters:
@@ -658,7 +728,7 @@ func (t *Logging) SetFormat(format _FORMAT) *Logging {
 // Returns:
 //   - *Logging: A Logging instance to enable method chaining.
 func (t *Logging) SetLevel(level LEVELTYPE) *Logging {
-	t._level = level
+	t._level.Store(int32(level))
 	return t
 }
 
@@ -712,7 +782,7 @@ func (t *Logging) SetRollingFileLoop(fileDir, fileName string, maxFileSize int64
 	if err = t._filehandler.openFileHandler(); err == nil {
 		t._isFileWell = true
 	}
-	t.err = err
+	t.setErr(err)
 	return t, err
 }
 
@@ -738,7 +808,7 @@ func (t *Logging) SetRollingByTime(fileDir, fileName string, mode _MODE_TIME) (l
 	if fileDir == "" {
 		fileDir, _ = os.Getwd()
 	}
-	t._fileDir, t._fileName, t._mode = fileDir, fileName, mode
+	t._fileDir, t._fileName, t._mode, t._cron = fileDir, fileName, mode, nil
 	t._cutmode = _TIMEMODE
 	if t._filehandler != nil {
 		t._filehandler.close()
@@ -748,7 +818,7 @@ func (t *Logging) SetRollingByTime(fileDir, fileName string, mode _MODE_TIME) (l
 		t._isFileWell = true
 		t.zeroTimer()
 	}
-	t.err = err
+	t.setErr(err)
 	return t, err
 }
 
@@ -756,9 +826,21 @@ func (t *Logging) SetRollingByTime(fileDir, fileName string, mode _MODE_TIME) (l
 //
 // Use SetOption() instead.
 func (t *Logging) SetGzipOn(is bool) *Logging {
-	t._gzip = is
+	// the cleanups of backups in the background read the compressor and the template
+	t.bgmu.Lock()
+	defer t.bgmu.Unlock()
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	t._compressor = nil
+	if is {
+		t._compressor = &GzipCompressor{}
+	}
 	if t._filehandler != nil {
-		t._filehandler._gzip = is
+		t._filehandler._compressor = t._compressor
+		// the template matches the backups by the suffix of the compressor
+		if t._filehandler._template != nil {
+			t._filehandler._template = parseBackupTemplate(t._backupname, t._fileName, t._mode, t._filehandler.zext())
+		}
 	}
 	return t
 }
@@ -787,18 +869,71 @@ func (t *Logging) SetOption(option *Option) *Logging {
 		t.newfileHandler()
 		if err := t._filehandler.openFileHandler(); err == nil {
 			t._isFileWell = true
+			if option.RotateOnStart && atomic.LoadInt64(&t._filehandler._fileSize) > 0 {
+				t._filehandler._fresh = true
+				t.rotateFile()
+			}
+			t._filehandler.retain()
+			if t._compressor != nil {
+				t._filehandler.tidyBackups()
+			}
 		} else {
 			fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
-			t.err = err
+			t.setOpenErr(err)
 		}
 
 		if t._cutmode&_TIMEMODE == _TIMEMODE {
 			t.zeroTimer()
 		}
+		t.watchFile(option.WatchInterval)
 	}
 	return t
 }
 
+// Rotate turns the log file into a backup and starts a new one right away, the same way as a rotation
+// triggered by size or time, and returns the full path of the backup, which may lie in the archive
+// directory. When the backup is to be compressed, the path is that of the compressed file, which appears
+// once the compression in the background is done; Close waits for it. An empty log file is not rotated.
+//
+// 立即切割日志文件，与按大小或时间触发的切割方式相同，返回备份文件的完整路径（可能位于归档目录中）。
+// 备份文件需要压缩时返回压缩后的文件路径，该文件在后台压缩完成后出现，Close会等待压缩完成。空日志文件不切割。
+func (t *Logging) Rotate() (bakfn string, err error) {
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	if !t._isFileWell {
+		return "", errors.New("no log file found")
+	}
+	if atomic.LoadInt64(&t._filehandler._fileSize) == 0 {
+		return
+	}
+	t._filehandler._fresh = true
+	var openFileErr error
+	if bakfn, err, openFileErr = t.rotateFile(); err == nil {
+		err = openFileErr
+	}
+	if zext := t._filehandler.zext(); bakfn != "" && zext != "" && t._filehandler._keepuncompressed <= 0 {
+		bakfn += zext
+	}
+	return
+}
+
+// Close stops the background work of the Logging instance and closes the log file.
+// Later log entries are only printed to the console.
+//
+// 停止后台任务并关闭日志文件，之后的日志只打印到控制台
+func (t *Logging) Close() (err error) {
+	defer t.bgwg.Wait()
+	t.zeroTimerStop()
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	t.watchFile(0)
+	if t._isFileWell {
+		t._isFileWell = false
+		err = t._filehandler.close()
+	}
+	return
+}
+
 func (t *Logging) getOptionArgs(option *Option) {
 	if option.Formatter != "" {
 		t._formatter = option.Formatter
@@ -813,13 +948,52 @@ func (t *Logging) getOptionArgs(option *Option) {
 	t.callDepth = option.CallDepth
 	t.customHandler = option.CustomHandler
 	t.stacktrace = option.Stacktrace
-	t._level = option.Level
+	t._level.Store(int32(option.Level))
+	t.setFlightRecorder(option.FlightRecorder, option.FlightMark)
+	if t.crashOutput && !option.CrashOutput {
+		setCrashOutput(nil)
+	}
+	t.crashOutput = option.CrashOutput
+	t.onFileEvent = option.OnFileEvent
 	if option.FileOption != nil {
 		t._cutmode = option.FileOption.Cutmode()
-		if t._cutmode != _TIMEMODE && t._cutmode != _SIZEMODE && t._cutmode != _MIXEDMODE {
+		if t._cutmode <= 0 || t._cutmode > _MIXEDMODE|_COUNTMODE {
 			t._cutmode = _MIXEDMODE
 		}
-		t._maxBackup, t._gzip = option.FileOption.MaxBackup(), option.FileOption.Compress()
+		t._maxBackup, t._compressor, t._keepUncompressed = option.FileOption.MaxBackup(), nil, 0
+		if o, ok := option.FileOption.(compressOption); ok {
+			t._compressor, t._keepUncompressed = o.Compressor(), o.KeepUncompressed()
+		}
+		if t._compressor == nil && option.FileOption.Compress() {
+			t._compressor = &GzipCompressor{}
+		}
+		t._gzipActive, t._sizeCompressed = false, false
+		if o, ok := option.FileOption.(gzipActiveOption); ok {
+			if t._gzipActive, t._sizeCompressed = o.GzipActive(), o.SizeCompressed(); t._gzipActive {
+				t._compressor = nil
+			}
+		}
+		t._maxAge, t._maxTotalSize = 0, 0
+		if o, ok := option.FileOption.(retentionOption); ok {
+			t._maxAge, t._maxTotalSize = o.MaxAge(), o.MaxTotalSize()
+		}
+		t._rotate, t._linkname = ROTATE_RENAME, ""
+		if o, ok := option.FileOption.(rotateOption); ok {
+			t._rotate, t._linkname = o.RotateMode(), o.LinkName()
+		}
+		t._backupname, t._archiveDir, t._archiveDated = "", "", false
+		if o, ok := option.FileOption.(backupOption); ok {
+			t._backupname, t._archiveDir, t._archiveDated = o.BackupName(), o.ArchiveDir(), o.ArchiveDated()
+		}
+		if t._cutmode&_COUNTMODE == _COUNTMODE {
+			t._maxRecords = 0
+			if o, ok := option.FileOption.(recordsOption); ok {
+				t._maxRecords = o.MaxRecords()
+			}
+			if t._maxRecords <= 0 {
+				t._maxRecords = math.MaxInt64
+			}
+		}
 		if t._cutmode&_SIZEMODE == _SIZEMODE {
 			t._maxSize, t._unit = option.FileOption.MaxSize(), 1
 			if t._maxSize <= 0 {
@@ -827,17 +1001,39 @@ func (t *Logging) getOptionArgs(option *Option) {
 			}
 		}
 		if t._cutmode&_TIMEMODE == _TIMEMODE {
-			t._mode = option.FileOption.TimeMode()
-			if t._mode != MODE_DAY && t._mode != MODE_HOUR && t._mode != MODE_MONTH {
+			t._mode, t._timeOffset, t._cron = option.FileOption.TimeMode(), 0, nil
+			if !t._mode.valid() {
 				t._mode = MODE_DAY
 			}
+			var expr string
+			if o, ok := option.FileOption.(scheduleOption); ok {
+				t._timeOffset, expr = o.TimeOffset(), o.CronExpr()
+			}
+			if expr != "" {
+				if c, err := parseCron(expr); err == nil {
+					t._mode, t._cron = _MODE_CRON, c
+				} else {
+					fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
+					t.setErr(err)
+				}
+			}
 		}
 	}
 }
 
 func (t *Logging) newfileHandler() {
 	t._filehandler = new(fileHandler)
-	t._filehandler.logger, t._filehandler._fileDir, t._filehandler._fileName, t._filehandler._maxSize, t._filehandler._cutmode, t._filehandler._unit, t._filehandler._maxbackup, t._filehandler._mode, t._filehandler._gzip = t, t._fileDir, t._fileName, t._maxSize, t._cutmode, t._unit, t._maxBackup, t._mode, t._gzip
+	t._filehandler.logger, t._filehandler._fileDir, t._filehandler._fileName, t._filehandler._maxSize, t._filehandler._cutmode, t._filehandler._unit, t._filehandler._maxbackup, t._filehandler._mode, t._filehandler._compressor = t, t._fileDir, t._fileName, t._maxSize, t._cutmode, t._unit, t._maxBackup, t._mode, t._compressor
+	t._filehandler._rotate, t._filehandler._linkname = t._rotate, t._linkname
+	t._filehandler._maxage, t._filehandler._maxtotalsize = t._maxAge, t._maxTotalSize
+	t._filehandler._keepuncompressed = t._keepUncompressed
+	t._filehandler._gzipactive, t._filehandler._sizecompressed = t._gzipActive, t._gzipActive && t._sizeCompressed
+	t._filehandler._archivedir, t._filehandler._archivedated = t._archiveDir, t._archiveDated
+	t._filehandler._offset, t._filehandler._cron = t._timeOffset, t._cron
+	t._filehandler._maxrecords = t._maxRecords
+	if t._backupname != "" {
+		t._filehandler._template = parseBackupTemplate(t._backupname, t._fileName, t._mode, t._filehandler.zext())
+	}
 }
 
 func (t *Logging) backUp() (bakfn string, err, openFileErr error) {
@@ -846,6 +1042,12 @@ func (t *Logging) backUp() (bakfn string, err, openFileErr error) {
 	if !t._filehandler.mustBackUp(0) {
 		return
 	}
+	return t.rotateFile()
+}
+
+// rotateFile closes the log file, turns it into a backup and opens a new one, and returns the path of the backup.
+// The caller holds the write lock.
+func (t *Logging) rotateFile() (bakfn string, err, openFileErr error) {
 	if err = t._filehandler.close(); err != nil {
 		fprintln(nil, t._format, LEVEL_ERROR, t.stacktrace, 1, nil, nil, err.Error())
 		return
@@ -862,19 +1064,48 @@ func (t *Logging) backUp() (bakfn string, err, openFileErr error) {
 
 	if openFileErr = t._filehandler.openFileHandler(); openFileErr != nil {
 		fprintln(nil, t._format, LEVEL_ERROR, t.stacktrace, 1, nil, nil, openFileErr.Error())
-		t.err = openFileErr
+		t.setOpenErr(openFileErr)
 	}
 	return
 }
 
+// setErr records err as the error that stops the logging, nil clears it.
+func (t *Logging) setErr(err error) {
+	if err == nil {
+		t.err.Store(nil)
+	} else {
+		t.err.Store(&err)
+	}
+	t.openErr = nil
+}
+
+// setOpenErr records the result of opening the log file. A failure stops the logging, and a later
+// success clears that failure, but not an error of another origin such as an invalid configuration.
+func (t *Logging) setOpenErr(err error) {
+	if err != nil {
+		t.openErr = &err
+		t.err.Store(t.openErr)
+	} else if t.openErr != nil {
+		t.err.CompareAndSwap(t.openErr, nil)
+		t.openErr = nil
+	}
+}
+
 func (t *Logging) println(format *string, _level LEVELTYPE, calldepth int, v ...any) *Logging {
-	if t._level > _level {
+	return t.printlnctx(nil, format, _level, k1(calldepth), v...)
+}
+
+func (t *Logging) printlnctx(ctx context.Context, format *string, _level LEVELTYPE, calldepth int, v ...any) *Logging {
+	if LEVELTYPE(t._level.Load()) > _level {
+		if t.flightRecorder != nil && _level > LEVEL_ALL && _level < LEVEL_OFF {
+			t.recordFlight(contextFields(ctx), format, _level, k1(calldepth), v...)
+		}
 		return t
 	}
-	if t.err != nil {
+	if t.err.Load() != nil {
 		return t
 	}
-	if t.customHandler != nil && !t.customHandler(&LogContext{Level: _level, Args: v}) {
+	if t.customHandler != nil && !t.customHandler(&LogContext{Level: _level, Args: v, Context: ctx}) {
 		return t
 	}
 	var buf *buffer.Buffer
@@ -883,45 +1114,28 @@ func (t *Logging) println(format *string, _level LEVELTYPE, calldepth int, v ...
 			buf.Free()
 		}
 	}()
-	var bs []byte
+	var bs, msg []byte
 	if t.callDepth > 0 {
 		calldepth += t.callDepth
 	}
-
-	if t._isFileWell {
-		if t._format != FORMAT_NANO {
-			if format == nil {
-				bs = fmt.Append([]byte{}, v...)
-			} else {
-				bs = fmt.Appendf([]byte{}, *format, v...)
-			}
-			if ol := t.leveloption[_level-1]; ol != nil {
-				buf = getOutBuffer(bs, _level, ol.Format, k1(calldepth), &ol.Formatter, t.stacktrace, t.attrFormat)
-			} else {
-				buf = getOutBuffer(bs, _level, t._format, k1(calldepth), &t._formatter, t.stacktrace, t.attrFormat)
-			}
-			if t.attrFormat != nil && t.attrFormat.SetBodyFmt != nil {
-				bs = t.attrFormat.SetBodyFmt(_level, buf.Bytes())
-			} else {
-				bs = buf.Bytes()
-			}
-		} else {
-			if format == nil {
-				bs = fmt.Appendln([]byte{}, v...)
-			} else {
-				bs = fmt.Appendf([]byte{}, *format+"\n", v...)
-			}
-		}
-		var openFileErr error
-		if t._filehandler.mustBackUp(len(bs)) {
-			_, openFileErr, _ = t.backUp()
-		}
-		if openFileErr == nil {
-			t._rwLock.RLock()
-			t._filehandler.write(bs)
-			t._rwLock.RUnlock()
+	if t.flightRecorder != nil && _level >= LEVEL_ERROR {
+		t.flushFlight()
+	}
+	fields := contextFields(ctx)
+	sinks := t.sinks.Load()
+	if t._isFileWell || sinks != nil || fields != nil {
+		msg, bs, buf = t.formatLine(fields, format, _level, k1(calldepth), v...)
+	}
+	if sinks != nil {
+		rec := newRecord(_level, k1(calldepth), msg, bs)
+		rec.Fields = fields
+		for _, s := range *sinks {
+			s.WriteRecord(rec)
 		}
 	}
+	if t._isFileWell {
+		t.writeLine(bs)
+	}
 	if t._isConsole {
 		if bs != nil {
 			consolewriter(bs, false)
@@ -936,6 +1150,55 @@ func (t *Logging) println(format *string, _level LEVELTYPE, calldepth int, v ...
 	return t
 }
 
+// writeLine writes a formatted line to the log file, rotating the file first when it is due.
+func (t *Logging) writeLine(bs []byte) {
+	var openFileErr error
+	if t._filehandler.mustBackUp(len(bs)) {
+		_, openFileErr, _ = t.backUp()
+	}
+	if openFileErr == nil {
+		t.writeFile(bs)
+	}
+}
+
+// formatLine formats a log entry the same way it is written to the log file.
+// It returns the plain message, the formatted line and the pooled buffer backing the line, which the caller must free.
+// Context fields are appended to the message unless the formatter places them with {field:name}.
+func (t *Logging) formatLine(fields []Field, format *string, _level LEVELTYPE, calldepth int, v ...any) (msg, bs []byte, buf *buffer.Buffer) {
+	if t._format != FORMAT_NANO {
+		if format == nil {
+			msg = fmt.Append([]byte{}, v...)
+		} else {
+			msg = fmt.Appendf([]byte{}, *format, v...)
+		}
+		formatter, flag := &t._formatter, t._format
+		if ol := t.leveloption[_level-1]; ol != nil {
+			formatter, flag = &ol.Formatter, ol.Format
+		}
+		body := msg
+		if fields != nil && !strings.Contains(*formatter, "{field:") {
+			body = appendFields(append([]byte{}, msg...), fields)
+		}
+		buf = getOutBuffer(body, _level, flag, k1(calldepth), formatter, t.stacktrace, t.attrFormat, fields)
+		if t.attrFormat != nil && t.attrFormat.SetBodyFmt != nil {
+			bs = t.attrFormat.SetBodyFmt(_level, buf.Bytes())
+		} else {
+			bs = buf.Bytes()
+		}
+	} else {
+		if format == nil {
+			bs = fmt.Appendln([]byte{}, v...)
+		} else {
+			bs = fmt.Appendf([]byte{}, *format+"\n", v...)
+		}
+		msg = bs[:len(bs)-1]
+		if fields != nil {
+			bs = append(appendFields(append([]byte{}, msg...), fields), '\n')
+		}
+	}
+	return
+}
+
 func SetLevelOption(level LEVELTYPE, option *LevelOption) *Logging {
 	return static_lo.SetLevelOption(level, option)
 }
@@ -948,21 +1211,40 @@ func (t *Logging) SetLevelOption(level LEVELTYPE, option *LevelOption) *Logging
 }
 
 type fileHandler struct {
-	logger     *Logging
-	fileHandle File
-	_fileDir   string
-	_fileName  string
-	file       *os.File
-	_maxSize   int64
-	_fileSize  int64
-	_fileSize2 int64
-	_lastPrint int64
-	_prevPrint int64
-	_unit      _UNIT
-	_cutmode   _CUTMODE
-	_maxbackup int
-	_gzip      bool
-	_mode      _MODE_TIME
+	logger            *Logging
+	fileHandle        io.WriteCloser
+	_fileDir          string
+	_fileName         string
+	file              *os.File
+	_maxSize          int64
+	_fileSize         int64
+	_fileSize2        int64
+	_lastPrint        int64
+	_prevPrint        int64
+	_unit             _UNIT
+	_cutmode          _CUTMODE
+	_maxbackup        int
+	_maxage           int
+	_maxtotalsize     int64
+	_compressor       Compressor
+	_keepuncompressed int
+	_gzipactive       bool
+	_sizecompressed   bool // _fileSize counts the compressed bytes of a gzip active file
+	_mode             _MODE_TIME
+	_rotate           _ROTATE
+	_linkname         string
+	_activeName       atomic.Pointer[string] // the file written to in ROTATE_LINK mode, read by the background cleanup
+	_template         *backupTemplate
+	_archivedir       string
+	_archivedated     bool
+	_fresh            bool // the next open starts a new file in ROTATE_LINK mode, even if the current one could be reused
+	_offset           time.Duration
+	_cron             *cronSchedule
+	_period           atomic.Pointer[[2]int64] // a write time and the end of its period
+	_maxrecords       int64
+	_records          atomic.Int64             // records written or reserved in the open file
+	_gzip             atomic.Pointer[gzipFile] // the open gzip active file, read without the lock to learn its compressed size
+	_tidying          atomic.Bool              // a cleanup of the backups is scheduled and has not started yet
 }
 
 func (t *fileHandler) openFileHandler() (e error) {
@@ -975,16 +1257,56 @@ func (t *fileHandler) openFileHandler() (e error) {
 		return
 	}
 	fname := filepath.Join(t._fileDir, t._fileName)
+	if t._rotate == ROTATE_LINK {
+		active := t.linkActiveName()
+		t._activeName.Store(&active)
+		t._fresh = false
+		fname = filepath.Join(t._fileDir, active)
+	}
 	if t.file, e = os.OpenFile(fname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666); e == nil {
-		t.fileHandle, e = New(t.file)
+		if t._gzipactive {
+			var g *gzipFile
+			if g, e = openGzipFile(t.file); e == nil {
+				t.fileHandle = g
+				t._gzip.Store(g)
+			} else if errors.Is(e, errNotGzip) {
+				return t.rotatePlain()
+			}
+		} else {
+			t._gzip.Store(nil)
+			t.fileHandle, e = New(t.file)
+		}
 	}
 	if e != nil {
 		fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, e.Error())
 		return
 	}
+	if t._rotate == ROTATE_LINK {
+		if err := t.updateLink(); err != nil {
+			fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
+		}
+	}
+	// crash output written raw would break the gzip stream of a gzip active file
+	if t.logger != nil && t.logger.crashOutput && !t._gzipactive {
+		if err := setCrashOutput(t.file); err != nil {
+			fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
+		}
+	}
+	g := t._gzip.Load()
+	if t._cutmode&_COUNTMODE == _COUNTMODE {
+		if g != nil {
+			t._records.Store(g.lines)
+		} else {
+			t._records.Store(countLines(t.file.Name()))
+		}
+	}
 	if fs, err := t.file.Stat(); err == nil {
-		t._fileSize = fs.Size()
-		atomic.StoreInt64(&t._fileSize2, t._fileSize)
+		size := fs.Size()
+		if g != nil && !t._sizecompressed {
+			size = g.raw
+		}
+		atomic.StoreInt64(&t._fileSize, size)
+		atomic.StoreInt64(&t._fileSize2, size)
 		t._lastPrint = fs.ModTime().Unix()
 	} else {
 		e = err
@@ -1000,7 +1322,9 @@ func (t *fileHandler) write(bs []byte) (n int, e error) {
 	defer recoverable(&e)
 	if bs != nil {
 		if n, e = t.fileHandle.Write(bs); e == nil {
-			if n > 0 {
+			if t._sizecompressed {
+				atomic.StoreInt64(&t._fileSize, t._gzip.Load().size.Load())
+			} else if n > 0 {
 				t.addFileSize(int64(n))
 			}
 			if t._cutmode&_TIMEMODE == _TIMEMODE {
@@ -1017,17 +1341,62 @@ func (t *fileHandler) write(bs []byte) (n int, e error) {
 	return
 }
 
+// reserveRecords takes a slot for the record bs in the open file in record-count mode and reports whether
+// one was left. A record is a single write, a log entry or a Write call, however many lines it spans, so a
+// multi-line entry such as a stack trace is never split across files.
+func (t *fileHandler) reserveRecords(bs []byte) bool {
+	if t._cutmode&_COUNTMODE == 0 || len(bs) == 0 {
+		return true
+	}
+	for {
+		r := t._records.Load()
+		if r >= t._maxrecords {
+			return false
+		}
+		if t._records.CompareAndSwap(r, r+1) {
+			return true
+		}
+	}
+}
+
+// countLines returns the number of lines in the file at path, taken as the records written by an earlier run,
+// whose boundaries are not known once they are in the file.
+func countLines(path string) (n int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	buf := make([]byte, 32<<10)
+	for {
+		c, err := f.Read(buf)
+		n += int64(bytes.Count(buf[:c], []byte{'\n'}))
+		if err != nil {
+			return
+		}
+	}
+}
+
 func (t *fileHandler) mustBackUp(addsize int) bool {
-	if t._fileSize == 0 {
+	if t._sizecompressed {
+		if g := t._gzip.Load(); g != nil {
+			atomic.StoreInt64(&t._fileSize, g.size.Load()) // members ended by the timer grow the file too
+		}
+	}
+	if atomic.LoadInt64(&t._fileSize) == 0 {
 		return false
 	}
 	if t._cutmode&_TIMEMODE == _TIMEMODE {
-		if t._lastPrint > 0 && !isCurrentTime(t._mode, t._lastPrint) {
+		if t._lastPrint > 0 && !t.isCurrentPeriod(t._lastPrint) {
 			return true
 		}
 	}
+	if t._cutmode&_COUNTMODE == _COUNTMODE && t._records.Load() >= t._maxrecords {
+		return true
+	}
 	if t._cutmode&_SIZEMODE == _SIZEMODE {
-		if addsize > 0 {
+		// the compressed size of a record is unknown before it is written
+		if addsize > 0 && !t._sizecompressed {
 			if atomic.AddInt64(&t._fileSize2, int64(addsize)) >= t._maxSize*int64(t._unit) {
 				return true
 			}
@@ -1037,32 +1406,135 @@ func (t *fileHandler) mustBackUp(addsize int) bool {
 	return false
 }
 
-func (t *fileHandler) rename() (bckupfilename string, err error) {
-	if t._cutmode&_TIMEMODE == _TIMEMODE {
-		bckupfilename = getBackupDayliFileName(t._lastPrint, t._fileDir, t._fileName, t._mode, t._gzip)
+// rename turns the log file into a backup and returns the path of the backup.
+func (t *fileHandler) rename() (bckupfile string, err error) {
+	if t._rotate == ROTATE_LINK {
+		bckupfile = filepath.Join(t._fileDir, t.activeName())
+		t.tidyBackups()
+		return
+	}
+	tm := t.backupTime()
+	dir := t.backupDir(tm)
+	if dir != t._fileDir {
+		if err = mkdirAll(dir); err != nil {
+			return
+		}
+	}
+	var bckupfilename string
+	if t._template != nil {
+		bckupfilename = t.templateBackupName(dir, tm)
+	} else if t._cutmode&_TIMEMODE == _TIMEMODE {
+		bckupfilename = getBackupDayliFileName(tm.Format(modeLayout(t._mode, false)), dir, t._fileName, t.zext())
 	} else {
-		bckupfilename, err = getBackupRollFileName(t._fileDir, t._fileName, t._gzip)
+		bckupfilename, err = getBackupRollFileName(dir, t._fileName, t.zext())
 	}
 	if bckupfilename != "" && err == nil {
+		if t._gzip.Load() != nil && !strings.HasSuffix(bckupfilename, _GZIP_EXT) {
+			bckupfilename += _GZIP_EXT
+		}
 		oldPath := filepath.Join(t._fileDir, t._fileName)
-		newPath := filepath.Join(t._fileDir, bckupfilename)
-		if err = os.Rename(oldPath, newPath); err == nil {
-			go func() {
-				defer recoverable(nil)
-				if t._gzip {
-					if err = lgzip(newPath+".gz", bckupfilename, newPath); err == nil {
-						os.Remove(newPath)
-					}
-				}
-				if t._maxbackup > 0 {
-					maxbackup(t._fileDir, t._fileName, t._maxbackup)
-				}
-			}()
+		newPath := filepath.Join(dir, bckupfilename)
+		if t._rotate == ROTATE_COPYTRUNCATE {
+			err = copyTruncate(oldPath, newPath)
+		} else {
+			err = moveFile(oldPath, newPath)
+		}
+		if err == nil {
+			bckupfile = newPath
+			t.tidyBackups()
 		}
 	}
 	return
 }
 
+// tidyBackups compresses the pending backups and removes the oldest ones in the background, once the
+// rotation in progress, if any, has opened the new file. The cleanups of a logger run one at a time, and the
+// rotations made while one is running are merged into a single next run, so that a burst of rotations does
+// not pile up goroutines. Close waits for the cleanup to finish.
+func (t *fileHandler) tidyBackups() {
+	if t._tidying.Swap(true) {
+		return
+	}
+	t.logger.background(func() {
+		t.logger.bgmu.Lock()
+		defer t.logger.bgmu.Unlock()
+		t._tidying.Store(false)
+		t.logger._rwLock.RLock()
+		t.logger._rwLock.RUnlock()
+		if t._compressor != nil {
+			t.compressBackups()
+		}
+		t.retain()
+	})
+}
+
+// compressBackups compresses the backups but the newest KeepUncompressed ones. It also finishes the work
+// of compressions interrupted by a crash: their temporary files are removed, and a compressed backup found
+// next to its original is discarded, as it may be incomplete, and made again from the original.
+func (t *fileHandler) compressBackups() {
+	zext := t.zext()
+	if zext == "" {
+		return
+	}
+	backups := t.backupFiles(true)
+	paths := make(map[string]bool, len(backups))
+	for _, b := range backups {
+		paths[b.path] = true
+	}
+	n := 0
+	for _, b := range backups {
+		if strings.HasSuffix(b.path, _TMPEXT) {
+			os.Remove(b.path)
+			continue
+		}
+		if trimZext(b.path, zext) != b.path {
+			if strings.HasSuffix(b.path, zext) && paths[strings.TrimSuffix(b.path, zext)] {
+				os.Remove(b.path)
+			} else {
+				n++
+			}
+			continue
+		}
+		if n++; n > t._keepuncompressed {
+			compressSem <- struct{}{}
+			err := compressFile(t._compressor, b.path)
+			<-compressSem
+			if err != nil {
+				fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, err.Error())
+			}
+		}
+	}
+}
+
+// retain removes the backups beyond the limits of MaxBackup, MaxAge and MaxTotalSize.
+func (t *fileHandler) retain() {
+	if t._maxbackup <= 0 && t._maxage <= 0 && t._maxtotalsize <= 0 {
+		return
+	}
+	maxbackup(t.archiveRoot(), t.backupFiles(false), t._maxbackup, time.Duration(t._maxage)*24*time.Hour, t._maxtotalsize)
+}
+
+// backupFiles lists the backups of the log file, the newest first. With tmp, the temporary files
+// of unfinished compressions are listed too.
+func (t *fileHandler) backupFiles(tmp bool) []backupFile {
+	// only an archive directory holds dated folders, the folders of the log directory are not ours to clean
+	dir, exclude := t.archiveRoot(), ""
+	recursive := t._archivedated && dir != t._fileDir
+	var pattern string
+	if t._rotate == ROTATE_LINK {
+		exclude, recursive, pattern = t.activeName(), false, t.linkPattern()
+	} else if t._template != nil {
+		pattern = t._template.re.String()
+	} else {
+		name, ext := splitExt(t._fileName)
+		pattern = fmt.Sprint("^", regexp.QuoteMeta(name), "(_\\d+){0,}", "_\\d+", regexp.QuoteMeta(ext), zextPattern(t.zext()), "$")
+	}
+	if tmp {
+		pattern = strings.TrimSuffix(pattern, "$") + "(" + regexp.QuoteMeta(_TMPEXT) + ")?$"
+	}
+	return listBackups(dir, pattern, exclude, t.backupKey, recursive)
+}
+
 func (t *fileHandler) close() (err error) {
 	defer recoverable(&err)
 	if t.fileHandle != nil {
@@ -1087,30 +1559,6 @@ func (t *fileHandler) close() (err error) {
 //	}
 //}
 
-func isCurrentTime(mode _MODE_TIME, timestamp int64) bool {
-	now := loctime()
-	switch mode {
-	case MODE_DAY:
-		return timestamp >= time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
-	case MODE_HOUR:
-		return timestamp >= time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location()).Unix()
-	case MODE_MONTH:
-		return timestamp >= time.Date(now.Year(), now.Month(), 0, 0, 0, 0, 0, now.Location()).Unix()
-	}
-	return false
-}
-
-func backupStr4Time(mode _MODE_TIME, now time.Time) string {
-	switch mode {
-	case MODE_HOUR:
-		return now.Format(_DATEFORMAT_HOUR)
-	case MODE_MONTH:
-		return now.Format(_DATEFORMAT_MONTH)
-	default:
-		return now.Format(_DATEFORMAT_DAY)
-	}
-}
-
 //func _yestStr(mode _MODE_TIME, now time.Time) string {
 //	//now := loctime()
 //	switch mode {
@@ -1125,8 +1573,7 @@ func backupStr4Time(mode _MODE_TIME, now time.Time) string {
 //	}
 //}
 
-func getBackupDayliFileName(unixTimestamp int64, dir, filename string, mode _MODE_TIME, isGzip bool) (bckupfilename string) {
-	timeStr := backupStr4Time(mode, time.Unix(unixTimestamp, 0))
+func getBackupDayliFileName(timeStr, dir, filename, zext string) (bckupfilename string) {
 	index := strings.LastIndex(filename, ".")
 	if index <= 0 {
 		index = len(filename)
@@ -1134,14 +1581,10 @@ func getBackupDayliFileName(unixTimestamp int64, dir, filename string, mode _MOD
 	fname := filename[:index]
 	suffix := filename[index:]
 	bckupfilename = fmt.Sprint(fname, "_", timeStr, suffix)
-	if isGzip {
-		if isFileExist(fmt.Sprint(filepath.Join(dir, bckupfilename), ".gz")) {
-			bckupfilename = _getBackupfilename(1, dir, fmt.Sprint(fname, "_", timeStr), suffix, isGzip)
-		}
-	} else {
-		if isFileExist(fmt.Sprint(filepath.Join(dir, bckupfilename))) {
-			bckupfilename = _getBackupfilename(1, dir, fmt.Sprint(fname, "_", timeStr), suffix, isGzip)
-		}
+	list, _ := _getDirList(dir)
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(fmt.Sprint(fname, "_", timeStr)) + `(?:_(\d+))?` + regexp.QuoteMeta(suffix) + zextPattern(zext) + `$`)
+	if seq, found := lastSeq(list, re); found {
+		bckupfilename = _getBackupfilename(seq+1, dir, fmt.Sprint(fname, "_", timeStr), suffix, zext)
 	}
 	return
 }
@@ -1155,7 +1598,7 @@ func _getDirList(dir string) ([]os.DirEntry, error) {
 	return f.ReadDir(-1)
 }
 
-func getBackupRollFileName(dir, filename string, isGzip bool) (bckupfilename string, er error) {
+func getBackupRollFileName(dir, filename, zext string) (bckupfilename string, er error) {
 	list, err := _getDirList(dir)
 	if err != nil {
 		er = err
@@ -1167,37 +1610,50 @@ func getBackupRollFileName(dir, filename string, isGzip bool) (bckupfilename str
 	}
 	fname := filename[:index]
 	suffix := filename[index:]
-	i := 1
+	seq, _ := lastSeq(list, regexp.MustCompile(`^`+regexp.QuoteMeta(fname)+`_(\d+)`+regexp.QuoteMeta(suffix)+zextPattern(zext)+`$`))
+	bckupfilename = _getBackupfilename(seq+1, dir, fname, suffix, zext)
+	return
+}
+
+// lastSeq returns the highest sequence, the first submatch of re, among the names of list that re matches,
+// and whether any name matched. New backups take the sequence after it rather than the lowest free one, so
+// that their names keep sorting after the older backups once retention has removed the first ones.
+func lastSeq(list []os.DirEntry, re *regexp.Regexp) (seq int, found bool) {
 	for _, fd := range list {
-		pattern := fmt.Sprint(`^`, fname, `_[\d]{1,}`, suffix, `$`)
-		if isGzip {
-			pattern = fmt.Sprint(`^`, fname, `_[\d]{1,}`, suffix, `.gz$`)
-		}
-		if matchString(pattern, fd.Name()) {
-			i++
+		if m := re.FindStringSubmatch(fd.Name()); m != nil {
+			found = true
+			if i, err := strconv.Atoi(m[1]); err == nil && i > seq {
+				seq = i
+			}
 		}
 	}
-	bckupfilename = _getBackupfilename(i, dir, fname, suffix, isGzip)
 	return
 }
 
-func _getBackupfilename(count int, dir, filename, suffix string, isGzip bool) (bckupfilename string) {
+func _getBackupfilename(count int, dir, filename, suffix, zext string) (bckupfilename string) {
 	bckupfilename = fmt.Sprint(filename, "_", count, suffix)
-	if isGzip {
-		if isFileExist(fmt.Sprint(filepath.Join(dir, bckupfilename), ".gz")) {
-			return _getBackupfilename(count+1, dir, filename, suffix, isGzip)
-		}
-	} else {
-		if isFileExist(fmt.Sprint(filepath.Join(dir, bckupfilename))) {
-			return _getBackupfilename(count+1, dir, filename, suffix, isGzip)
-		}
+	if backupExist(filepath.Join(dir, bckupfilename), zext) {
+		return _getBackupfilename(count+1, dir, filename, suffix, zext)
 	}
 	return
 }
 
+// backupExist reports whether the backup at path exists, either as it is or compressed with the suffix zext.
+func backupExist(path, zext string) bool {
+	if isFileExist(path) {
+		return true
+	}
+	for _, e := range zexts(zext) {
+		if isFileExist(path + e) {
+			return true
+		}
+	}
+	return false
+}
+
 func consolewrite(s []byte, level, stacktrace LEVELTYPE, flag _FORMAT, calldepth int, formatter *string, attrFormat *AttrFormat) {
 	if flag != FORMAT_NANO {
-		buf := getOutBuffer(s, level, flag, k1(calldepth), formatter, stacktrace, attrFormat)
+		buf := getOutBuffer(s, level, flag, k1(calldepth), formatter, stacktrace, attrFormat, nil)
 		defer buf.Free()
 		if attrFormat != nil && attrFormat.SetBodyFmt != nil {
 			consolewriter(attrFormat.SetBodyFmt(level, buf.Bytes()), false)
@@ -1214,10 +1670,14 @@ func consolewrite(s []byte, level, stacktrace LEVELTYPE, flag _FORMAT, calldepth
 }
 
 func consolewriter(bs []byte, newline bool) {
+	out := os.Stdout
+	if f := consoleFile.Load(); f != nil {
+		out = f
+	}
 	if newline {
-		os.Stdout.Write(append(bs, '\n'))
+		out.Write(append(bs, '\n'))
 	} else {
-		os.Stdout.Write(bs)
+		out.Write(bs)
 	}
 }
 
@@ -1225,8 +1685,8 @@ func k1(calldepth int) int {
 	return calldepth + 1
 }
 
-func getOutBuffer(s []byte, level LEVELTYPE, format _FORMAT, calldepth int, formatter *string, stacktrace LEVELTYPE, attrFormat *AttrFormat) *buffer.Buffer {
-	return output(format, k1(calldepth), s, level, formatter, stacktrace, attrFormat)
+func getOutBuffer(s []byte, level LEVELTYPE, format _FORMAT, calldepth int, formatter *string, stacktrace LEVELTYPE, attrFormat *AttrFormat, fields []Field) *buffer.Buffer {
+	return output(format, k1(calldepth), s, level, formatter, stacktrace, attrFormat, fields)
 }
 
 func mkdirAll(dir string) (e error) {
@@ -1242,35 +1702,109 @@ func mkdirAll(dir string) (e error) {
 	return
 }
 
-func maxbackup(dir, filename string, maxcount int) {
-	ext := filepath.Ext(filename)
-	name := filename[:len(filename)-len(ext)]
-	if entries, err := os.ReadDir(dir); err == nil {
-		if len(entries) > maxcount {
-			sort.Slice(entries, func(i, j int) bool {
-				f1, _ := entries[i].Info()
-				f2, _ := entries[j].Info()
-				return f1.ModTime().Unix() < f2.ModTime().Unix()
-			})
-			rms := make([]string, 0)
-			for _, entry := range entries {
-				if !entry.IsDir() {
-					parrent := fmt.Sprint("^", name, "(_\\d+){0,}", "_\\d+", ext, "(\\.gz){0,}$")
-					if matchString(parrent, entry.Name()) {
-						filePath := filepath.Join(dir, entry.Name())
-						rms = append(rms, filePath)
-					}
-				}
+type backupFile struct {
+	path  string
+	entry os.DirEntry
+	key   []int64
+}
+
+// listBackups returns the files matching pattern in dir, but exclude, the newest first. Backups are ordered
+// by the key that key derives from their names, so copying or touching a backup does not change which one
+// is the oldest. With recursive, the backups in the dated subfolders of dir are included too, ordered by
+// their folder first.
+func listBackups(dir, pattern, exclude string, key func(string) []int64, recursive bool) []backupFile {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	backups := make([]backupFile, 0)
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != exclude && re.MatchString(entry.Name()) {
+			k := key(entry.Name())
+			if rel, err := filepath.Rel(dir, filepath.Dir(path)); err == nil && rel != "." {
+				k = append(numericKey(rel), k...)
+			}
+			backups = append(backups, backupFile{path, entry, k})
+		}
+		return nil
+	})
+	sort.Slice(backups, func(i, j int) bool {
+		if c := compareKey(backups[i].key, backups[j].key); c != 0 {
+			return c > 0
+		}
+		return backups[i].path > backups[j].path
+	})
+	return backups
+}
+
+// maxbackup keeps, from the newest, the backups while they are fewer than maxcount, younger than maxage
+// and within maxtotal bytes altogether; the others are removed, and so are the subfolders of dir they leave
+// empty. Zero disables a limit.
+func maxbackup(dir string, backups []backupFile, maxcount int, maxage time.Duration, maxtotal int64) {
+	if maxage <= 0 && maxtotal <= 0 && len(backups) <= maxcount {
+		return
+	}
+	total := int64(0)
+	for i, b := range backups {
+		remove := maxcount > 0 && i >= maxcount
+		if !remove && (maxage > 0 || maxtotal > 0) {
+			fi, err := b.entry.Info()
+			if err != nil {
+				continue
 			}
-			if len(rms) > maxcount {
-				for i := 0; i < len(rms)-maxcount; i++ {
-					os.Remove(rms[i])
+			total += fi.Size()
+			remove = (maxage > 0 && time.Since(fi.ModTime()) > maxage) || (maxtotal > 0 && total > maxtotal)
+		}
+		if remove {
+			os.Remove(b.path)
+			for d := filepath.Dir(b.path); d != dir && len(d) > len(dir); d = filepath.Dir(d) {
+				if os.Remove(d) != nil {
+					break
 				}
 			}
 		}
 	}
 }
 
+// copyTruncate copies src to dst and truncates src in place, keeping its inode.
+func copyTruncate(src, dst string) (err error) {
+	if err = copyFile(src, dst); err == nil {
+		err = os.Truncate(src, 0)
+	}
+	return
+}
+
+// copyFile copies src to the new file dst and syncs it. An incomplete dst is removed.
+func copyFile(src, dst string) (err error) {
+	var f1, f2 *os.File
+	if f1, err = os.Open(src); err != nil {
+		return
+	}
+	defer f1.Close()
+	if f2, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666); err != nil {
+		return
+	}
+	if _, err = io.Copy(f2, f1); err == nil {
+		err = f2.Sync()
+	}
+	if e := f2.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return
+}
+
 func isFileExist(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil || os.IsExist(err)
@@ -1300,35 +1834,17 @@ func loctime() time.Time {
 	}
 }
 
-func lgzip(gzfile, gzname, srcfile string) (err error) {
-	var gf *os.File
-	if gf, err = os.Create(gzfile); err == nil {
-		defer gf.Close()
-		var f1 *os.File
-		if f1, err = os.Open(srcfile); err == nil {
-			defer f1.Close()
-			gw := gzip.NewWriter(gf)
-			defer gw.Close()
-			gw.Header.Name = gzname
-			var buf bytes.Buffer
-			io.Copy(&buf, f1)
-			_, err = gw.Write(buf.Bytes())
-		}
-	}
-	return
-}
-
 var m = hashmap.NewLimitHashMap[uintptr, runtime.Frame](1 << 13)
 
-func output(flag _FORMAT, calldepth int, s []byte, level LEVELTYPE, formatter *string, stacktrace LEVELTYPE, attrFormat *AttrFormat) (buf *buffer.Buffer) {
+func output(flag _FORMAT, calldepth int, s []byte, level LEVELTYPE, formatter *string, stacktrace LEVELTYPE, attrFormat *AttrFormat, fields []Field) (buf *buffer.Buffer) {
 	var callstack *callStack
 	if flag&(FORMAT_SHORTFILENAME|FORMAT_LONGFILENAME|FORMAT_RELATIVEFILENAME) != 0 {
 		callstack = collectCallStack(k1(calldepth), flag&FORMAT_FUNC != 0, callstack, stacktrace > LEVEL_ALL && stacktrace <= level)
 	}
-	return formatmsg(s, loctime(), callstack, flag, level, formatter, attrFormat)
+	return formatmsg(s, loctime(), callstack, flag, level, formatter, attrFormat, fields)
 }
 
-func formatmsg(msg []byte, t time.Time, callstack *callStack, flag _FORMAT, level LEVELTYPE, formatter *string, attrFormat *AttrFormat) (buf *buffer.Buffer) {
+func formatmsg(msg []byte, t time.Time, callstack *callStack, flag _FORMAT, level LEVELTYPE, formatter *string, attrFormat *AttrFormat, fields []Field) (buf *buffer.Buffer) {
 	buf = buffer.NewBufferByPool()
 	var levelbuf, timebuf, filebuf *buffer.Buffer
 	is_default_formatter := formatter == nil || *formatter == ""
@@ -1398,12 +1914,12 @@ func formatmsg(msg []byte, t time.Time, callstack *callStack, flag _FORMAT, leve
 		buf.Write(msg)
 		buf.WriteByte('\n')
 	} else {
-		parseAndFormatLog(formatter, buf, levelbuf, timebuf, filebuf, msg)
+		parseAndFormatLog(formatter, buf, levelbuf, timebuf, filebuf, msg, fields)
 	}
 	return
 }
 
-func parseAndFormatLog(formatStr *string, buf, levelbuf, timebuf, filebuf *buffer.Buffer, msg []byte) {
+func parseAndFormatLog(formatStr *string, buf, levelbuf, timebuf, filebuf *buffer.Buffer, msg []byte, fields []Field) {
 	if formatStr == nil || *formatStr == "" {
 		buf.Write(msg)
 		return
@@ -1423,6 +1939,10 @@ func parseAndFormatLog(formatStr *string, buf, levelbuf, timebuf, filebuf *buffe
 					buf.Write(filebuf.Bytes())
 				case "message":
 					buf.Write(msg)
+				default:
+					if key, ok := strings.CutPrefix(placeholder, "field:"); ok {
+						appendFieldValue(buf, fields, key)
+					}
 				}
 				placeholder = ""
 			} else {
@@ -1464,7 +1984,7 @@ func (t *Logging) zeroTimer() {
 	if t.atStart.CompareAndSwap(0, 1) {
 		defer t.atStart.Store(0)
 		if t.tmTimer == nil {
-			t.tmTimer = time.AfterFunc(timeUntilNextWholeHour(), t.zeroCheck)
+			t.tmTimer = time.AfterFunc(t.untilNextPeriod(), t.zeroCheck)
 		}
 	}
 }
@@ -1476,12 +1996,3 @@ func (t *Logging) zeroCheck() {
 	}
 	t.zeroTimer()
 }
-
-func timeUntilNextWholeHour() (r time.Duration) {
-	now := time.Now()
-	nextWholeHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 1, 0, now.Location())
-	if r = nextWholeHour.Sub(now); r < time.Second {
-		r = time.Second
-	}
-	return
-}
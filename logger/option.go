@@ -7,6 +7,11 @@
 
 package logger
 
+import (
+	"context"
+	"time"
+)
+
 // FileOption defines the configuration interface for log file rotation.
 // It provides settings for file rotation mode, time-based rotation, file path,
 // maximum file size, maximum backup count, and compression options.
@@ -21,13 +26,77 @@ type FileOption interface {
 	Compress() bool       // Returns whether compression is enabled / 返回是否启用压缩
 }
 
+// The interfaces below are optional settings of a FileOption. They are found by type assertion, so a
+// FileOption that leaves one out keeps the default behaviour. The built-in modes implement all of them.
+// 以下接口为FileOption的可选配置，通过类型断言获取，未实现时使用默认行为。内置的切割模式均已实现。
+
+// scheduleOption shifts or replaces the time-based rotation boundaries.
+// scheduleOption 用于偏移或替换按时间切割的切割点。
+type scheduleOption interface {
+	TimeOffset() time.Duration // Returns the shift of the time-based rotation boundaries / 返回时间切割点的偏移量
+	CronExpr() string          // Returns the cron expression of the rotation schedule / 返回切割计划的cron表达式
+}
+
+// recordsOption limits the number of records in a file.
+// recordsOption 用于限制单个文件的记录数。
+type recordsOption interface {
+	MaxRecords() int64 // Returns the maximum number of records in a file / 返回单个文件的最大记录数
+}
+
+// compressOption sets how backup files are compressed.
+// compressOption 用于设置备份文件的压缩方式。
+type compressOption interface {
+	Compressor() Compressor // Returns how backup files are compressed / 返回备份文件的压缩方式
+	KeepUncompressed() int  // Returns the number of newest backups left uncompressed / 返回不压缩的最新备份文件数量
+}
+
+// gzipActiveOption writes the active file as a gzip stream.
+// gzipActiveOption 用于以gzip流写入活动日志文件。
+type gzipActiveOption interface {
+	GzipActive() bool     // Returns whether the active file is written as a gzip stream / 返回活动日志文件是否以gzip流写入
+	SizeCompressed() bool // Returns whether MaxSize counts compressed bytes of a gzip active file / 返回MaxSize是否按gzip活动日志文件的压缩后字节计算
+}
+
+// rotateOption sets how the active file is turned into a backup.
+// rotateOption 用于设置活动日志文件转为备份文件的方式。
+type rotateOption interface {
+	RotateMode() _ROTATE // Returns how the active file is turned into a backup / 返回活动日志文件转为备份文件的方式
+	LinkName() string    // Returns the name of the symlink to the active file in ROTATE_LINK mode / 返回ROTATE_LINK模式下指向活动日志文件的符号链接名
+}
+
+// backupOption sets the names and the location of backup files.
+// backupOption 用于设置备份文件的名称与存放位置。
+type backupOption interface {
+	BackupName() string // Returns the backup file name template / 返回备份文件名模板
+	ArchiveDir() string // Returns the directory backup files are moved to / 返回备份文件的归档目录
+	ArchiveDated() bool // Returns whether backups are archived into YYYY/MM/DD subfolders / 返回是否按YYYY/MM/DD子目录归档
+}
+
+// retentionOption limits backup files by age and combined size.
+// retentionOption 用于按保留时间与总大小限制备份文件。
+type retentionOption interface {
+	MaxAge() int         // Returns the maximum age of backup files in days / 返回备份文件保留的最大天数
+	MaxTotalSize() int64 // Returns the maximum combined size of backup files (in bytes) / 返回备份文件的最大总大小（字节）
+}
+
 // FileSizeMode defines the configuration for file rotation based on file size.
 // FileSizeMode 定义了按文件大小切割的配置。
 type FileSizeMode struct {
-	Filename   string // The path to the log file / 日志文件路径
-	Maxsize    int64  // The maximum file size; when exceeded, a rotation will occur / 文件最大大小，超过该大小时进行切割
-	Maxbackup  int    // The maximum number of backup files to keep / 保留的最大备份文件数量
-	IsCompress bool   // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Filename         string     // The path to the log file / 日志文件路径
+	Maxsize          int64      // The maximum file size; when exceeded, a rotation will occur / 文件最大大小，超过该大小时进行切割
+	Maxbackup        int        // The maximum number of backup files to keep / 保留的最大备份文件数量
+	Maxage           int        // Backups older than Maxage days are deleted, <= 0 keeps them regardless of age / 删除超过Maxage天的备份文件，小于等于0时不限制
+	Maxtotalsize     int64      // The newest backups are kept within this combined size, <= 0 means no limit / 按从新到旧保留总大小不超过该值的备份文件，小于等于0时不限制
+	IsCompress       bool       // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Compression      Compressor // How backups are compressed, gzip when nil and IsCompress is set / 备份文件的压缩方式，为nil且IsCompress为true时使用gzip
+	Keepuncompressed int        // The newest backups left uncompressed for easy grepping, older ones are compressed / 最新的若干个备份文件不压缩以便检索，更早的备份文件才压缩
+	Gzipactive       bool       // The active file is written as a gzip stream, closing a member every second so that it stays readable after a crash; backups are then not compressed again / 活动日志文件以gzip流写入，每秒结束一个gzip成员以保证崩溃后仍可读取，此时备份文件不再压缩
+	Sizecompressed   bool       // With Gzipactive, Maxsize counts the compressed bytes on disk instead of the uncompressed bytes logged / 启用Gzipactive时，Maxsize按磁盘上压缩后的字节计算，默认按压缩前的日志字节计算
+	Rotatemode       _ROTATE    // How the active file is turned into a backup, ROTATE_RENAME by default / 活动日志文件转为备份文件的方式，默认ROTATE_RENAME
+	Linkname         string     // Name of the symlink in the log directory in ROTATE_LINK mode, the file name by default / ROTATE_LINK模式下日志目录中符号链接的名称，默认为日志文件名
+	Backupname       string     // Backup file name template, e.g. "{name}-{date:2006-01-02T15}.{seq}{ext}", the built-in names by default / 备份文件名模板，默认使用内置命名
+	Archivedir       string     // Directory the backups are moved to, relative to the log directory unless absolute; the log directory by default / 备份文件的归档目录，相对路径基于日志目录，默认为日志目录
+	Archivedated     bool       // Whether backups go into YYYY/MM/DD subfolders of Archivedir / 是否将备份文件归档到Archivedir下的YYYY/MM/DD子目录
 }
 
 func (f *FileSizeMode) Cutmode() _CUTMODE {
@@ -38,6 +107,14 @@ func (f *FileSizeMode) TimeMode() _MODE_TIME {
 	return MODE_HOUR // This function is not used in this mode
 }
 
+func (f *FileSizeMode) TimeOffset() time.Duration {
+	return 0 // This function is not used in this mode
+}
+
+func (f *FileSizeMode) CronExpr() string {
+	return "" // This function is not used in this mode
+}
+
 func (f *FileSizeMode) FilePath() string {
 	return f.Filename // Returns the log file path / 返回日志文件路径
 }
@@ -46,21 +123,81 @@ func (f *FileSizeMode) MaxSize() int64 {
 	return f.Maxsize // Returns the maximum file size limit / 返回最大文件大小限制
 }
 
+func (f *FileSizeMode) MaxRecords() int64 {
+	return 0 // This function is not used in this mode
+}
+
 func (f *FileSizeMode) MaxBackup() int {
 	return f.Maxbackup // Returns the maximum number of backup files / 返回最大备份文件数量
 }
 
+func (f *FileSizeMode) MaxAge() int {
+	return f.Maxage // Returns the maximum age of backup files in days / 返回备份文件保留的最大天数
+}
+
+func (f *FileSizeMode) MaxTotalSize() int64 {
+	return f.Maxtotalsize // Returns the maximum combined size of backup files / 返回备份文件的最大总大小
+}
+
 func (f *FileSizeMode) Compress() bool {
 	return f.IsCompress // Returns whether compression is enabled / 返回是否启用压缩
 }
 
+func (f *FileSizeMode) Compressor() Compressor {
+	return f.Compression // Returns the compressor / 返回压缩方式
+}
+
+func (f *FileSizeMode) KeepUncompressed() int {
+	return f.Keepuncompressed // Returns the number of backups left uncompressed / 返回不压缩的备份文件数量
+}
+
+func (f *FileSizeMode) GzipActive() bool {
+	return f.Gzipactive // Returns whether the active file is gzip compressed / 返回活动日志文件是否gzip压缩
+}
+
+func (f *FileSizeMode) SizeCompressed() bool {
+	return f.Sizecompressed // Returns whether Maxsize counts compressed bytes / 返回Maxsize是否按压缩后字节计算
+}
+
+func (f *FileSizeMode) RotateMode() _ROTATE {
+	return f.Rotatemode // Returns the rotation strategy / 返回切割方式
+}
+
+func (f *FileSizeMode) LinkName() string {
+	return f.Linkname // Returns the symlink name / 返回符号链接名
+}
+
+func (f *FileSizeMode) BackupName() string {
+	return f.Backupname // Returns the backup file name template / 返回备份文件名模板
+}
+
+func (f *FileSizeMode) ArchiveDir() string {
+	return f.Archivedir // Returns the archive directory / 返回归档目录
+}
+
+func (f *FileSizeMode) ArchiveDated() bool {
+	return f.Archivedated // Returns whether dated subfolders are used / 返回是否使用日期子目录
+}
+
 // FileTimeMode defines the configuration for file rotation based on time.
 // FileTimeMode 定义了按时间切割的配置。
 type FileTimeMode struct {
-	Filename   string     // The path to the log file / 日志文件路径
-	Timemode   _MODE_TIME // The time-based rotation mode / 时间切割模式
-	Maxbackup  int        // The maximum number of backup files to keep / 保留的最大备份文件数量
-	IsCompress bool       // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Filename         string        // The path to the log file / 日志文件路径
+	Timemode         _MODE_TIME    // The time-based rotation mode / 时间切割模式
+	Timeoffset       time.Duration // Shift of the rotation boundaries, e.g. 2*time.Hour rotates daily at 02:00 / 切割时间点的偏移量，如2*time.Hour表示每天2点切割
+	Cronexpr         string        // Cron expression of the rotation schedule, e.g. "0 2 * * *"; it takes precedence over Timemode / 切割计划的cron表达式，如"0 2 * * *"，设置后优先于Timemode
+	Maxbackup        int           // The maximum number of backup files to keep / 保留的最大备份文件数量
+	Maxage           int           // Backups older than Maxage days are deleted, <= 0 keeps them regardless of age / 删除超过Maxage天的备份文件，小于等于0时不限制
+	Maxtotalsize     int64         // The newest backups are kept within this combined size, <= 0 means no limit / 按从新到旧保留总大小不超过该值的备份文件，小于等于0时不限制
+	IsCompress       bool          // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Compression      Compressor    // How backups are compressed, gzip when nil and IsCompress is set / 备份文件的压缩方式，为nil且IsCompress为true时使用gzip
+	Keepuncompressed int           // The newest backups left uncompressed for easy grepping, older ones are compressed / 最新的若干个备份文件不压缩以便检索，更早的备份文件才压缩
+	Gzipactive       bool          // The active file is written as a gzip stream, closing a member every second so that it stays readable after a crash; backups are then not compressed again / 活动日志文件以gzip流写入，每秒结束一个gzip成员以保证崩溃后仍可读取，此时备份文件不再压缩
+	Rotatemode       _ROTATE       // How the active file is turned into a backup, ROTATE_RENAME by default / 活动日志文件转为备份文件的方式，默认ROTATE_RENAME
+	Linkname         string        // Name of the symlink in the log directory in ROTATE_LINK mode, the file name by default / ROTATE_LINK模式下日志目录中符号链接的名称，默认为日志文件名
+	Backupname       string        // Backup file name template, e.g. "{name}-{date:2006-01-02T15}.{seq}{ext}", the built-in names by default / 备份文件名模板，默认使用内置命名
+	Archivedir       string        // Directory the backups are moved to, relative to the log directory unless absolute; the log directory by default / 备份文件的归档目录，相对路径基于日志目录，默认为日志目录
+	Archivedated     bool          // Whether backups go into YYYY/MM/DD subfolders of Archivedir / 是否将备份文件归档到Archivedir下的YYYY/MM/DD子目录
 }
 
 func (f *FileTimeMode) Cutmode() _CUTMODE {
@@ -71,6 +208,14 @@ func (f *FileTimeMode) TimeMode() _MODE_TIME {
 	return f.Timemode // Returns the time-based rotation mode / 返回时间切割模式
 }
 
+func (f *FileTimeMode) TimeOffset() time.Duration {
+	return f.Timeoffset // Returns the shift of the rotation boundaries / 返回切割时间点的偏移量
+}
+
+func (f *FileTimeMode) CronExpr() string {
+	return f.Cronexpr // Returns the cron expression / 返回cron表达式
+}
+
 func (f *FileTimeMode) FilePath() string {
 	return f.Filename // Returns the log file path / 返回日志文件路径
 }
@@ -79,25 +224,90 @@ func (f *FileTimeMode) MaxSize() int64 {
 	return 0 // No size limitation for time-based rotation / 按时间切割不考虑文件大小限制
 }
 
+func (f *FileTimeMode) MaxRecords() int64 {
+	return 0 // This function is not used in this mode
+}
+
 func (f *FileTimeMode) MaxBackup() int {
 	return f.Maxbackup // Returns the maximum number of backup files / 返回最大备份文件数量
 }
 
+func (f *FileTimeMode) MaxAge() int {
+	return f.Maxage // Returns the maximum age of backup files in days / 返回备份文件保留的最大天数
+}
+
+func (f *FileTimeMode) MaxTotalSize() int64 {
+	return f.Maxtotalsize // Returns the maximum combined size of backup files / 返回备份文件的最大总大小
+}
+
 func (f *FileTimeMode) Compress() bool {
 	return f.IsCompress // Returns whether compression is enabled / 返回是否启用压缩
 }
 
+func (f *FileTimeMode) Compressor() Compressor {
+	return f.Compression // Returns the compressor / 返回压缩方式
+}
+
+func (f *FileTimeMode) KeepUncompressed() int {
+	return f.Keepuncompressed // Returns the number of backups left uncompressed / 返回不压缩的备份文件数量
+}
+
+func (f *FileTimeMode) GzipActive() bool {
+	return f.Gzipactive // Returns whether the active file is gzip compressed / 返回活动日志文件是否gzip压缩
+}
+
+func (f *FileTimeMode) SizeCompressed() bool {
+	return false // This function is not used in this mode
+}
+
+func (f *FileTimeMode) RotateMode() _ROTATE {
+	return f.Rotatemode // Returns the rotation strategy / 返回切割方式
+}
+
+func (f *FileTimeMode) LinkName() string {
+	return f.Linkname // Returns the symlink name / 返回符号链接名
+}
+
+func (f *FileTimeMode) BackupName() string {
+	return f.Backupname // Returns the backup file name template / 返回备份文件名模板
+}
+
+func (f *FileTimeMode) ArchiveDir() string {
+	return f.Archivedir // Returns the archive directory / 返回归档目录
+}
+
+func (f *FileTimeMode) ArchiveDated() bool {
+	return f.Archivedated // Returns whether dated subfolders are used / 返回是否使用日期子目录
+}
+
 // FileMixedMode defines the configuration for file rotation based on both time and file size.
 // FileMixedMode 定义了按时间和文件大小混合切割的配置。
 type FileMixedMode struct {
-	Filename   string     // The path to the log file / 日志文件路径
-	Timemode   _MODE_TIME // The time-based rotation mode / 时间切割模式
-	Maxsize    int64      // The maximum file size; when exceeded, a rotation will occur / 文件最大大小，超过该大小时进行切割
-	Maxbackup  int        // The maximum number of backup files to keep / 保留的最大备份文件数量
-	IsCompress bool       // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Filename         string        // The path to the log file / 日志文件路径
+	Timemode         _MODE_TIME    // The time-based rotation mode / 时间切割模式
+	Timeoffset       time.Duration // Shift of the rotation boundaries, e.g. 2*time.Hour rotates daily at 02:00 / 切割时间点的偏移量，如2*time.Hour表示每天2点切割
+	Cronexpr         string        // Cron expression of the rotation schedule, e.g. "0 2 * * *"; it takes precedence over Timemode / 切割计划的cron表达式，如"0 2 * * *"，设置后优先于Timemode
+	Maxsize          int64         // The maximum file size; when exceeded, a rotation will occur / 文件最大大小，超过该大小时进行切割
+	Maxrecords       int64         // The maximum number of records in a file, 0 disables rotation by record count / 单个文件的最大记录数，0表示不按记录数切割
+	Maxbackup        int           // The maximum number of backup files to keep / 保留的最大备份文件数量
+	Maxage           int           // Backups older than Maxage days are deleted, <= 0 keeps them regardless of age / 删除超过Maxage天的备份文件，小于等于0时不限制
+	Maxtotalsize     int64         // The newest backups are kept within this combined size, <= 0 means no limit / 按从新到旧保留总大小不超过该值的备份文件，小于等于0时不限制
+	IsCompress       bool          // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Compression      Compressor    // How backups are compressed, gzip when nil and IsCompress is set / 备份文件的压缩方式，为nil且IsCompress为true时使用gzip
+	Keepuncompressed int           // The newest backups left uncompressed for easy grepping, older ones are compressed / 最新的若干个备份文件不压缩以便检索，更早的备份文件才压缩
+	Gzipactive       bool          // The active file is written as a gzip stream, closing a member every second so that it stays readable after a crash; backups are then not compressed again / 活动日志文件以gzip流写入，每秒结束一个gzip成员以保证崩溃后仍可读取，此时备份文件不再压缩
+	Sizecompressed   bool          // With Gzipactive, Maxsize counts the compressed bytes on disk instead of the uncompressed bytes logged / 启用Gzipactive时，Maxsize按磁盘上压缩后的字节计算，默认按压缩前的日志字节计算
+	Rotatemode       _ROTATE       // How the active file is turned into a backup, ROTATE_RENAME by default / 活动日志文件转为备份文件的方式，默认ROTATE_RENAME
+	Linkname         string        // Name of the symlink in the log directory in ROTATE_LINK mode, the file name by default / ROTATE_LINK模式下日志目录中符号链接的名称，默认为日志文件名
+	Backupname       string        // Backup file name template, e.g. "{name}-{date:2006-01-02T15}.{seq}{ext}", the built-in names by default / 备份文件名模板，默认使用内置命名
+	Archivedir       string        // Directory the backups are moved to, relative to the log directory unless absolute; the log directory by default / 备份文件的归档目录，相对路径基于日志目录，默认为日志目录
+	Archivedated     bool          // Whether backups go into YYYY/MM/DD subfolders of Archivedir / 是否将备份文件归档到Archivedir下的YYYY/MM/DD子目录
 }
 
 func (f *FileMixedMode) Cutmode() _CUTMODE {
+	if f.Maxrecords > 0 {
+		return _MIXEDMODE | _COUNTMODE // Also rotates by record count / 同时按记录数切割
+	}
 	return _MIXEDMODE // Indicates rotation by both time and size (mixed mode) / 表示按时间和大小进行混合切割
 }
 
@@ -105,6 +315,14 @@ func (f *FileMixedMode) TimeMode() _MODE_TIME {
 	return f.Timemode // Returns the time-based rotation mode / 返回时间切割模式
 }
 
+func (f *FileMixedMode) TimeOffset() time.Duration {
+	return f.Timeoffset // Returns the shift of the rotation boundaries / 返回切割时间点的偏移量
+}
+
+func (f *FileMixedMode) CronExpr() string {
+	return f.Cronexpr // Returns the cron expression / 返回cron表达式
+}
+
 func (f *FileMixedMode) FilePath() string {
 	return f.Filename // Returns the log file path / 返回日志文件路径
 }
@@ -113,14 +331,161 @@ func (f *FileMixedMode) MaxSize() int64 {
 	return f.Maxsize // Returns the maximum file size for rotation / 返回文件最大大小
 }
 
+func (f *FileMixedMode) MaxRecords() int64 {
+	return f.Maxrecords // Returns the maximum number of records in a file / 返回单个文件的最大记录数
+}
+
 func (f *FileMixedMode) MaxBackup() int {
 	return f.Maxbackup // Returns the maximum number of backup files / 返回最大备份文件数量
 }
 
+func (f *FileMixedMode) MaxAge() int {
+	return f.Maxage // Returns the maximum age of backup files in days / 返回备份文件保留的最大天数
+}
+
+func (f *FileMixedMode) MaxTotalSize() int64 {
+	return f.Maxtotalsize // Returns the maximum combined size of backup files / 返回备份文件的最大总大小
+}
+
 func (f *FileMixedMode) Compress() bool {
 	return f.IsCompress // Returns whether compression is enabled / 返回是否启用压缩
 }
 
+func (f *FileMixedMode) Compressor() Compressor {
+	return f.Compression // Returns the compressor / 返回压缩方式
+}
+
+func (f *FileMixedMode) KeepUncompressed() int {
+	return f.Keepuncompressed // Returns the number of backups left uncompressed / 返回不压缩的备份文件数量
+}
+
+func (f *FileMixedMode) GzipActive() bool {
+	return f.Gzipactive // Returns whether the active file is gzip compressed / 返回活动日志文件是否gzip压缩
+}
+
+func (f *FileMixedMode) SizeCompressed() bool {
+	return f.Sizecompressed // Returns whether Maxsize counts compressed bytes / 返回Maxsize是否按压缩后字节计算
+}
+
+func (f *FileMixedMode) RotateMode() _ROTATE {
+	return f.Rotatemode // Returns the rotation strategy / 返回切割方式
+}
+
+func (f *FileMixedMode) LinkName() string {
+	return f.Linkname // Returns the symlink name / 返回符号链接名
+}
+
+func (f *FileMixedMode) BackupName() string {
+	return f.Backupname // Returns the backup file name template / 返回备份文件名模板
+}
+
+func (f *FileMixedMode) ArchiveDir() string {
+	return f.Archivedir // Returns the archive directory / 返回归档目录
+}
+
+func (f *FileMixedMode) ArchiveDated() bool {
+	return f.Archivedated // Returns whether dated subfolders are used / 返回是否使用日期子目录
+}
+
+// FileCountMode defines the configuration for file rotation based on the number of records.
+// FileCountMode 定义了按记录数切割的配置。
+type FileCountMode struct {
+	Filename         string     // The path to the log file / 日志文件路径
+	Maxrecords       int64      // The maximum number of records in a file; when reached, a rotation will occur / 单个文件的最大记录数，达到该数量时进行切割
+	Maxbackup        int        // The maximum number of backup files to keep / 保留的最大备份文件数量
+	Maxage           int        // Backups older than Maxage days are deleted, <= 0 keeps them regardless of age / 删除超过Maxage天的备份文件，小于等于0时不限制
+	Maxtotalsize     int64      // The newest backups are kept within this combined size, <= 0 means no limit / 按从新到旧保留总大小不超过该值的备份文件，小于等于0时不限制
+	IsCompress       bool       // Whether to enable compression for backup files / 是否启用备份文件的压缩
+	Compression      Compressor // How backups are compressed, gzip when nil and IsCompress is set / 备份文件的压缩方式，为nil且IsCompress为true时使用gzip
+	Keepuncompressed int        // The newest backups left uncompressed for easy grepping, older ones are compressed / 最新的若干个备份文件不压缩以便检索，更早的备份文件才压缩
+	Gzipactive       bool       // The active file is written as a gzip stream, closing a member every second so that it stays readable after a crash; backups are then not compressed again / 活动日志文件以gzip流写入，每秒结束一个gzip成员以保证崩溃后仍可读取，此时备份文件不再压缩
+	Rotatemode       _ROTATE    // How the active file is turned into a backup, ROTATE_RENAME by default / 活动日志文件转为备份文件的方式，默认ROTATE_RENAME
+	Linkname         string     // Name of the symlink in the log directory in ROTATE_LINK mode, the file name by default / ROTATE_LINK模式下日志目录中符号链接的名称，默认为日志文件名
+	Backupname       string     // Backup file name template, e.g. "{name}-{date:2006-01-02T15}.{seq}{ext}", the built-in names by default / 备份文件名模板，默认使用内置命名
+	Archivedir       string     // Directory the backups are moved to, relative to the log directory unless absolute; the log directory by default / 备份文件的归档目录，相对路径基于日志目录，默认为日志目录
+	Archivedated     bool       // Whether backups go into YYYY/MM/DD subfolders of Archivedir / 是否将备份文件归档到Archivedir下的YYYY/MM/DD子目录
+}
+
+func (f *FileCountMode) Cutmode() _CUTMODE {
+	return _COUNTMODE // Indicates rotation by record count / 表示按记录数进行切割
+}
+
+func (f *FileCountMode) TimeMode() _MODE_TIME {
+	return MODE_HOUR // This function is not used in this mode
+}
+
+func (f *FileCountMode) TimeOffset() time.Duration {
+	return 0 // This function is not used in this mode
+}
+
+func (f *FileCountMode) CronExpr() string {
+	return "" // This function is not used in this mode
+}
+
+func (f *FileCountMode) FilePath() string {
+	return f.Filename // Returns the log file path / 返回日志文件路径
+}
+
+func (f *FileCountMode) MaxSize() int64 {
+	return 0 // This function is not used in this mode
+}
+
+func (f *FileCountMode) MaxRecords() int64 {
+	return f.Maxrecords // Returns the maximum number of records in a file / 返回单个文件的最大记录数
+}
+
+func (f *FileCountMode) MaxBackup() int {
+	return f.Maxbackup // Returns the maximum number of backup files / 返回最大备份文件数量
+}
+
+func (f *FileCountMode) MaxAge() int {
+	return f.Maxage // Returns the maximum age of backup files in days / 返回备份文件保留的最大天数
+}
+
+func (f *FileCountMode) MaxTotalSize() int64 {
+	return f.Maxtotalsize // Returns the maximum combined size of backup files / 返回备份文件的最大总大小
+}
+
+func (f *FileCountMode) Compress() bool {
+	return f.IsCompress // Returns whether compression is enabled / 返回是否启用压缩
+}
+
+func (f *FileCountMode) Compressor() Compressor {
+	return f.Compression // Returns the compressor / 返回压缩方式
+}
+
+func (f *FileCountMode) KeepUncompressed() int {
+	return f.Keepuncompressed // Returns the number of backups left uncompressed / 返回不压缩的备份文件数量
+}
+
+func (f *FileCountMode) GzipActive() bool {
+	return f.Gzipactive // Returns whether the active file is gzip compressed / 返回活动日志文件是否gzip压缩
+}
+
+func (f *FileCountMode) SizeCompressed() bool {
+	return false // This function is not used in this mode
+}
+
+func (f *FileCountMode) RotateMode() _ROTATE {
+	return f.Rotatemode // Returns the rotation strategy / 返回切割方式
+}
+
+func (f *FileCountMode) LinkName() string {
+	return f.Linkname // Returns the symlink name / 返回符号链接名
+}
+
+func (f *FileCountMode) BackupName() string {
+	return f.Backupname // Returns the backup file name template / 返回备份文件名模板
+}
+
+func (f *FileCountMode) ArchiveDir() string {
+	return f.Archivedir // Returns the archive directory / 返回归档目录
+}
+
+func (f *FileCountMode) ArchiveDated() bool {
+	return f.Archivedated // Returns whether dated subfolders are used / 返回是否使用日期子目录
+}
+
 // Option represents a configuration option for the Logging struct.
 // It includes various settings such as log level, console output, format, formatter, file options, and a custom handler.
 type Option struct {
@@ -144,11 +509,45 @@ type Option struct {
 
 	// CallDepth Custom function call depth
 	CallDepth int
+
+	// FlightRecorder is the number of records below Level kept in memory. When an ERROR or FATAL record is logged,
+	// the kept records are written out first, each line prefixed with FlightMark. 0 disables the flight recorder.
+	//
+	// FlightRecorder 低于Level的日志在内存中保留的条数，打印ERROR或FATAL日志时先输出这些日志，每行以FlightMark为前缀。0表示不启用。
+	FlightRecorder int
+
+	// FlightMark is the prefix of back-filled lines, default "[FLIGHT]".
+	FlightMark string
+
+	// CrashOutput makes the runtime also write fatal crash reports, such as unrecovered panics and fatal errors,
+	// to the current log file. It follows the file across rotations and requires go1.23 or later.
+	//
+	// CrashOutput 使运行时将致命崩溃信息（如未捕获的panic）同时写入当前日志文件，文件切割后自动跟随新文件，需要go1.23及以上版本。
+	CrashOutput bool
+
+	// RotateOnStart rotates a non-empty log file when the option is applied, so that every process start
+	// begins with a fresh file.
+	//
+	// RotateOnStart 应用配置时切割非空的日志文件，使每次进程启动都从新文件开始写入。
+	RotateOnStart bool
+
+	// WatchInterval is how often the log file is checked for having been moved or deleted by someone else.
+	// When the path no longer refers to the open file, the file is reopened and OnFileEvent is called. 0 disables the check.
+	//
+	// WatchInterval 检查日志文件是否被外部移动或删除的时间间隔，发现路径不再指向已打开的文件时重新打开文件并调用OnFileEvent。0表示不检查。
+	WatchInterval time.Duration
+
+	// OnFileEvent is called when something happens to the log file outside of the normal write path.
+	// If nil, events carrying an error are printed to the console.
+	//
+	// OnFileEvent 日志文件在正常写入之外发生事件时调用，为nil时有错误的事件打印到控制台。
+	OnFileEvent func(ev *FileEvent)
 }
 
 type LogContext struct {
-	Level LEVELTYPE
-	Args  []any
+	Level   LEVELTYPE
+	Args    []any
+	Context context.Context // The context passed to the *Context logging methods, nil otherwise.
 }
 
 type LevelOption struct {
@@ -0,0 +1,61 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// RecoverAndLog recovers a panic and logs it with the full stack at the FATAL level using the default logging instance.
+// It must be called directly by defer.
+//
+// 捕获panic并以FATAL级别打印panic信息及完整堆栈，必须直接通过defer调用
+//
+// e.g.
+//
+//	go func() {
+//		defer logger.RecoverAndLog()
+//		...
+//	}()
+func RecoverAndLog() {
+	if e := recover(); e != nil {
+		static_lo.logPanic(e)
+	}
+}
+
+// Go runs fn in a new goroutine, logging a panic of fn with the default logging instance instead of crashing the process.
+//
+// 在新协程中执行fn，fn发生panic时打印日志而不是使进程崩溃
+func Go(fn func()) {
+	static_lo.Go(fn)
+}
+
+// RecoverAndLog recovers a panic and logs it with the full stack at the FATAL level.
+// It must be called directly by defer.
+//
+// 捕获panic并以FATAL级别打印panic信息及完整堆栈，必须直接通过defer调用
+func (t *Logging) RecoverAndLog() {
+	if e := recover(); e != nil {
+		t.logPanic(e)
+	}
+}
+
+// Go runs fn in a new goroutine, logging a panic of fn instead of crashing the process.
+//
+// 在新协程中执行fn，fn发生panic时打印日志而不是使进程崩溃
+func (t *Logging) Go(fn func()) {
+	go func() {
+		defer t.RecoverAndLog()
+		fn()
+	}()
+}
+
+func (t *Logging) logPanic(e any) {
+	t.printlnctx(nil, nil, LEVEL_FATAL, 3, fmt.Sprintf("panic: %v\n%s", e, debug.Stack()))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"runtime"
+	"time"
+)
+
+// Record is a single log entry as seen by a Sink.
+// It carries the structured attributes of the entry together with the formatted line written to the log file.
+// Record 是Sink接收到的单条日志，包含结构化属性及写入日志文件的格式化文本。
+type Record struct {
+	Time    time.Time `json:"time"`             // Time the entry was logged / 日志时间
+	Level   LEVELTYPE `json:"level"`            // Log level of the entry / 日志级别
+	File    string    `json:"file"`             // Full path of the caller file / 调用者文件路径
+	Line    int       `json:"line"`             // Line number of the caller / 调用者行号
+	Message string    `json:"message"`          // Log message without any formatting / 未格式化的日志内容
+	Fields  Fields    `json:"fields,omitempty"` // Values extracted from the context of the call / 从context中提取的字段
+	Raw     []byte    `json:"-"`                // Formatted line, including the trailing newline / 格式化后的日志行
+}
+
+// Sink receives every record accepted by a Logging instance.
+// WriteRecord is called synchronously on the logging goroutine, so implementations must be safe for
+// concurrent use and should return quickly. The record must not be modified.
+// Sink 接收Logging实例输出的每一条日志，WriteRecord在打印日志的协程中同步调用，实现必须并发安全且尽快返回。
+type Sink interface {
+	WriteRecord(r *Record)
+}
+
+// AddSink registers a sink on the default logging instance.
+func AddSink(s Sink) *Logging {
+	return static_lo.AddSink(s)
+}
+
+// RemoveSink unregisters a sink from the default logging instance.
+func RemoveSink(s Sink) *Logging {
+	return static_lo.RemoveSink(s)
+}
+
+// AddSink registers a sink that receives every record logged at or above the current level.
+//
+// 注册Sink，接收所有满足日志级别的日志记录
+func (t *Logging) AddSink(s Sink) *Logging {
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	var sinks []Sink
+	if p := t.sinks.Load(); p != nil {
+		sinks = append(sinks, *p...)
+	}
+	sinks = append(sinks, s)
+	t.sinks.Store(&sinks)
+	return t
+}
+
+// RemoveSink unregisters a sink previously added by AddSink.
+//
+// 移除由AddSink注册的Sink
+func (t *Logging) RemoveSink(s Sink) *Logging {
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	p := t.sinks.Load()
+	if p == nil {
+		return t
+	}
+	sinks := make([]Sink, 0, len(*p))
+	for _, v := range *p {
+		if v != s {
+			sinks = append(sinks, v)
+		}
+	}
+	if len(sinks) == 0 {
+		t.sinks.Store(nil)
+	} else {
+		t.sinks.Store(&sinks)
+	}
+	return t
+}
+
+func newRecord(level LEVELTYPE, calldepth int, msg, line []byte) *Record {
+	r := &Record{Time: loctime(), Level: level, Message: string(msg), Raw: append([]byte{}, line...)}
+	if _, file, l, ok := runtime.Caller(calldepth); calldepth > 0 && ok {
+		r.File, r.Line = file, l
+	}
+	return r
+}
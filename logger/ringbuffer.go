@@ -0,0 +1,143 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query filters the records held by a RingBuffer. Zero values disable the corresponding filter.
+// Query 用于过滤RingBuffer中的日志记录，零值表示不过滤。
+type Query struct {
+	Level    LEVELTYPE // Minimum log level / 最低日志级别
+	Since    time.Time // Only records logged at or after this time / 起始时间（包含）
+	Until    time.Time // Only records logged before this time / 截止时间（不包含）
+	File     string    // Substring of the caller file path / 调用者文件路径包含的字符串
+	Contains string    // Substring of the log message / 日志内容包含的字符串
+	Limit    int       // Maximum number of records returned, the newest are kept / 返回的最大记录数，保留最新的记录
+}
+
+func (q *Query) match(r *Record) bool {
+	if q == nil {
+		return true
+	}
+	if r.Level < q.Level {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	if q.File != "" && !strings.Contains(r.File, q.File) {
+		return false
+	}
+	if q.Contains != "" && !strings.Contains(r.Message, q.Contains) {
+		return false
+	}
+	return true
+}
+
+// RingBuffer is a Sink that keeps the most recent records in memory.
+// It is bounded by the number of records, by the total size of the formatted lines, or by both.
+//
+// RingBuffer 是在内存中保存最近日志的Sink，可按记录数、格式化日志总字节数或两者同时限制。
+//
+// e.g.
+//
+//	rb := NewRingBuffer(1000, 1<<20)
+//	log.AddSink(rb)
+//	records := rb.Query(&Query{Level: LEVEL_WARN, Contains: "timeout"})
+type RingBuffer struct {
+	mu         sync.RWMutex
+	records    []*Record
+	size       int64
+	maxRecords int
+	maxBytes   int64
+}
+
+// NewRingBuffer creates a RingBuffer holding at most maxRecords records and maxBytes bytes of formatted lines.
+// A value <= 0 disables the corresponding limit; if both are disabled the buffer keeps 1000 records.
+//
+// Parameters:
+//   - maxRecords: maximum number of records kept
+//   - maxBytes: maximum total size of the formatted lines kept
+func NewRingBuffer(maxRecords int, maxBytes int64) *RingBuffer {
+	if maxRecords <= 0 && maxBytes <= 0 {
+		maxRecords = 1000
+	}
+	return &RingBuffer{maxRecords: maxRecords, maxBytes: maxBytes}
+}
+
+// WriteRecord implements Sink.
+func (b *RingBuffer) WriteRecord(r *Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = append(b.records, r)
+	b.size += int64(len(r.Raw))
+	for len(b.records) > 1 && ((b.maxRecords > 0 && len(b.records) > b.maxRecords) || (b.maxBytes > 0 && b.size > b.maxBytes)) {
+		b.size -= int64(len(b.records[0].Raw))
+		b.records[0] = nil
+		b.records = b.records[1:]
+	}
+}
+
+// Query returns the records matching q in chronological order. A nil query returns all records.
+//
+// 按时间顺序返回满足条件的日志记录，q为nil时返回全部记录
+func (b *RingBuffer) Query(q *Query) []*Record {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	rs := make([]*Record, 0)
+	for _, r := range b.records {
+		if q.match(r) {
+			rs = append(rs, r)
+		}
+	}
+	if q != nil && q.Limit > 0 && len(rs) > q.Limit {
+		rs = rs[len(rs)-q.Limit:]
+	}
+	return rs
+}
+
+// Lines returns the formatted lines of the records matching q in chronological order.
+//
+// 按时间顺序返回满足条件的格式化日志行
+func (b *RingBuffer) Lines(q *Query) [][]byte {
+	rs := b.Query(q)
+	lines := make([][]byte, len(rs))
+	for i, r := range rs {
+		lines[i] = r.Raw
+	}
+	return lines
+}
+
+// Len returns the number of records currently held.
+func (b *RingBuffer) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.records)
+}
+
+// Reset discards all records.
+func (b *RingBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records, b.size = nil, 0
+}
+
+// drain removes and returns all records.
+func (b *RingBuffer) drain() (rs []*Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rs, b.records, b.size = b.records, nil, 0
+	return
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"time"
+)
+
+const (
+	_MODE_MINUTE _MODE_TIME = 5 // every n minutes, see MODE_MINUTE
+	_MODE_CRON   _MODE_TIME = 6 // set when a cron expression is configured
+)
+
+const (
+	_DATEFORMAT_MINUTE = "200601021504"
+	_LINKFORMAT_MINUTE = "2006-01-02T1504"
+)
+
+// MODE_MINUTE rotates every n minutes, counted from midnight, e.g. MODE_MINUTE(15) rotates at :00, :15, :30 and :45.
+// MODE_MINUTE 每n分钟切割一次，从零点开始计算，如MODE_MINUTE(15)在每小时的0、15、30、45分切割
+func MODE_MINUTE(n int) _MODE_TIME {
+	if n <= 0 {
+		n = 1
+	} else if n > 1440 {
+		n = 1440
+	}
+	return _MODE_MINUTE | _MODE_TIME(n)<<8
+}
+
+// MODE_WEEK_FROM rotates weekly, the weeks starting on the given weekday.
+// MODE_WEEK_FROM 按周切割，每周从指定的星期几开始
+func MODE_WEEK_FROM(d time.Weekday) _MODE_TIME {
+	return MODE_WEEK | _MODE_TIME(d%7+1)<<8
+}
+
+func (m _MODE_TIME) kind() _MODE_TIME {
+	return m & 0xff
+}
+
+func (m _MODE_TIME) param() int {
+	return int(m >> 8)
+}
+
+func (m _MODE_TIME) valid() bool {
+	switch m.kind() {
+	case MODE_HOUR, MODE_DAY, MODE_MONTH, _MODE_CRON:
+		return m.param() == 0
+	case MODE_WEEK:
+		return m.param() <= 7
+	case _MODE_MINUTE:
+		return m.param() >= 1 && m.param() <= 1440
+	}
+	return false
+}
+
+// weekStart returns the first day of the week in MODE_WEEK, Monday unless set by MODE_WEEK_FROM.
+func (m _MODE_TIME) weekStart() time.Weekday {
+	if p := m.param(); p > 0 {
+		return time.Weekday(p - 1)
+	}
+	return time.Monday
+}
+
+// modeLayout returns the time layout naming a period of mode: compact for the built-in backup names,
+// ISO style for templates and ROTATE_LINK.
+func modeLayout(mode _MODE_TIME, iso bool) string {
+	switch mode.kind() {
+	case MODE_HOUR:
+		if iso {
+			return _LINKFORMAT_HOUR
+		}
+		return _DATEFORMAT_HOUR
+	case MODE_MONTH:
+		if iso {
+			return _LINKFORMAT_MONTH
+		}
+		return _DATEFORMAT_MONTH
+	case _MODE_MINUTE, _MODE_CRON:
+		if iso {
+			return _LINKFORMAT_MINUTE
+		}
+		return _DATEFORMAT_MINUTE
+	default:
+		if iso {
+			return _LINKFORMAT_DAY
+		}
+		return _DATEFORMAT_DAY
+	}
+}
+
+// startOfDay returns the first instant of the given date in loc: midnight, or the end of the daylight saving
+// gap in zones that skip midnight, where time.Date resolves to an hour of the day before.
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	for noon := time.Date(year, month, day, 12, 0, 0, 0, loc); t.Day() != noon.Day(); {
+		t = t.Add(time.Hour)
+	}
+	return t
+}
+
+// periodStart returns the start of the rotation period containing tm.
+func (t *fileHandler) periodStart(tm time.Time) time.Time {
+	if t._mode.kind() == _MODE_CRON && t._cron != nil {
+		return t._cron.prev(tm)
+	}
+	u := tm.Add(-t._offset)
+	day := startOfDay(u.Year(), u.Month(), u.Day(), u.Location())
+	switch t._mode.kind() {
+	case _MODE_MINUTE:
+		n, m := t._mode.param(), int(u.Sub(day)/time.Minute)
+		day = day.Add(time.Duration(m-m%n) * time.Minute)
+	case MODE_HOUR:
+		day = time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), 0, 0, 0, u.Location())
+	case MODE_WEEK:
+		day = startOfDay(u.Year(), u.Month(), u.Day()-(int(u.Weekday())-int(t._mode.weekStart())+7)%7, u.Location())
+	case MODE_MONTH:
+		day = startOfDay(u.Year(), u.Month(), 1, u.Location())
+	}
+	return day.Add(t._offset)
+}
+
+// periodNext returns the start of the rotation period following the one containing tm. The wall clock time
+// of the next boundary may fall into a daylight saving gap and resolve to an instant not after tm, so the
+// boundary is moved on by whole hours until it lies ahead.
+func (t *fileHandler) periodNext(tm time.Time) time.Time {
+	if t._mode.kind() == _MODE_CRON && t._cron != nil {
+		return t._cron.next(tm)
+	}
+	u, s := tm.Add(-t._offset), t.periodStart(tm).Add(-t._offset)
+	var next time.Time
+	switch t._mode.kind() {
+	case _MODE_MINUTE:
+		next = s.Add(time.Duration(t._mode.param()) * time.Minute)
+		if end := startOfDay(s.Year(), s.Month(), s.Day()+1, s.Location()); next.After(end) {
+			next = end
+		}
+	case MODE_HOUR:
+		next = time.Date(s.Year(), s.Month(), s.Day(), s.Hour()+1, 0, 0, 0, s.Location())
+	case MODE_WEEK:
+		next = startOfDay(s.Year(), s.Month(), s.Day()+7, s.Location())
+	case MODE_MONTH:
+		next = startOfDay(s.Year(), s.Month()+1, 1, s.Location())
+	default:
+		next = startOfDay(s.Year(), s.Month(), s.Day()+1, s.Location())
+	}
+	for !next.After(u) {
+		next = next.Add(time.Hour)
+	}
+	return next.Add(t._offset)
+}
+
+// isCurrentPeriod reports whether the unix time ts falls in the current rotation period.
+// The end of the period of ts is cached, as it is checked on every write.
+func (t *fileHandler) isCurrentPeriod(ts int64) bool {
+	p := t._period.Load()
+	if p == nil || ts < p[0] || ts >= p[1] {
+		p = &[2]int64{ts, t.periodNext(time.Unix(ts, 0)).Unix()}
+		t._period.Store(p)
+	}
+	return loctime().Unix() < p[1]
+}
+
+// untilNextPeriod returns the time left until the next rotation boundary.
+func (t *Logging) untilNextPeriod() (r time.Duration) {
+	now := loctime()
+	if r = t._filehandler.periodNext(now).Sub(now); r < 10*time.Millisecond {
+		r = 10 * time.Millisecond
+	}
+	return
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// notifySignal calls fn on its own goroutine each time one of sigs is received, until stop is called.
+func notifySignal(sigs []os.Signal, fn func(os.Signal)) (stop func()) {
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch, done := make(chan os.Signal, 1), make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				func() {
+					defer recoverable(nil)
+					fn(sig)
+				}()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// Reopen closes the log file and opens it again by the same path, without renaming it.
+// It is meant to be used after an external tool such as logrotate has moved the file away.
+//
+// 关闭并按原路径重新打开日志文件，不进行重命名，用于logrotate等外部工具移走日志文件之后
+func (t *Logging) Reopen() (err error) {
+	t._rwLock.Lock()
+	defer t._rwLock.Unlock()
+	if !t._isFileWell {
+		return errors.New("no log file found")
+	}
+	t._filehandler.close()
+	if err = t._filehandler.openFileHandler(); err != nil {
+		fprintln(nil, t._format, LEVEL_ERROR, t.stacktrace, 1, nil, nil, err.Error())
+	}
+	t.setOpenErr(err)
+	return
+}
+
+// ReopenOnSignal calls Reopen each time one of sigs is received. Without sigs it listens for SIGHUP
+// (not available on windows). The returned function stops listening.
+//
+// 收到指定信号时重新打开日志文件，未指定信号时监听SIGHUP（windows不支持），返回的函数用于停止监听
+func (t *Logging) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = reopenSignals
+	}
+	return notifySignal(sigs, func(os.Signal) { t.Reopen() })
+}
+
+// DebugOnSignal lowers the log level to LEVEL_DEBUG for the duration d each time one of sigs is received,
+// then restores the previous level. A signal received while the level is lowered restarts the duration.
+// Without sigs it listens for SIGUSR2 (not available on windows). The returned function stops listening
+// and restores the level if it is still lowered.
+//
+// 收到指定信号时将日志级别临时调整为LEVEL_DEBUG，持续d后恢复原级别，期间再次收到信号则重新计时。
+// 未指定信号时监听SIGUSR2（windows不支持），返回的函数用于停止监听并恢复日志级别
+func (t *Logging) DebugOnSignal(d time.Duration, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = debugSignals
+	}
+	var mu sync.Mutex
+	var timer *time.Timer
+	var prev LEVELTYPE
+	restore := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+			t.SetLevel(prev)
+		}
+	}
+	stopSignal := notifySignal(sigs, func(os.Signal) {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Reset(d)
+			return
+		}
+		if prev = LEVELTYPE(t._level.Load()); prev <= LEVEL_DEBUG {
+			return
+		}
+		t.SetLevel(LEVEL_DEBUG)
+		timer = time.AfterFunc(d, restore)
+	})
+	return func() {
+		stopSignal()
+		restore()
+	}
+}
+
+// DumpOnSignal writes a runtime snapshot into the log file, see DumpRuntime, each time one of sigs is received.
+// Without sigs it listens for SIGUSR1 (not available on windows). The returned function stops listening.
+//
+// 收到指定信号时将运行时快照写入日志文件，未指定信号时监听SIGUSR1（windows不支持），返回的函数用于停止监听
+func (t *Logging) DumpOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = dumpSignals
+	}
+	return notifySignal(sigs, func(os.Signal) { t.DumpRuntime() })
+}
+
+// DumpRuntime writes a full goroutine dump, memory statistics and GC statistics into the log file as one block
+// delimited by begin and end lines. The block is written like any other entry, so a large dump triggers rotation.
+//
+// 将完整的协程堆栈、内存统计与GC统计作为一个带起止标记的整体写入日志文件，写入时同样遵循日志文件切割规则
+func (t *Logging) DumpRuntime() (err error) {
+	if !t._isFileWell {
+		return errors.New("no log file found")
+	}
+	_, err = t.Write(runtimeDump())
+	return
+}
+
+func runtimeDump() []byte {
+	var buf bytes.Buffer
+	now := loctime().Format("2006-01-02 15:04:05.000000")
+	fmt.Fprintf(&buf, "======== runtime dump begin %s ========\n", now)
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	fmt.Fprintf(&buf, "---- runtime ----\ngoroutines: %d\nnumcpu: %d\ngomaxprocs: %d\nversion: %s\n", runtime.NumGoroutine(), runtime.NumCPU(), runtime.GOMAXPROCS(0), runtime.Version())
+	fmt.Fprintf(&buf, "---- memstats ----\nalloc: %d\ntotal_alloc: %d\nsys: %d\nmallocs: %d\nfrees: %d\nheap_alloc: %d\nheap_sys: %d\nheap_idle: %d\nheap_inuse: %d\nheap_released: %d\nheap_objects: %d\nstack_inuse: %d\nstack_sys: %d\nnext_gc: %d\nnum_gc: %d\ngc_cpu_fraction: %f\n",
+		ms.Alloc, ms.TotalAlloc, ms.Sys, ms.Mallocs, ms.Frees, ms.HeapAlloc, ms.HeapSys, ms.HeapIdle, ms.HeapInuse, ms.HeapReleased, ms.HeapObjects, ms.StackInuse, ms.StackSys, ms.NextGC, ms.NumGC, ms.GCCPUFraction)
+	var gs debug.GCStats
+	gs.PauseQuantiles = make([]time.Duration, 5)
+	debug.ReadGCStats(&gs)
+	fmt.Fprintf(&buf, "---- gcstats ----\nnum_gc: %d\nlast_gc: %s\npause_total: %s\npause_quantiles: %v\n", gs.NumGC, gs.LastGC.Format("2006-01-02 15:04:05.000000"), gs.PauseTotal, gs.PauseQuantiles)
+	buf.WriteString("---- goroutines ----\n")
+	stack := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(stack, true)
+		if n < len(stack) {
+			stack = stack[:n]
+			break
+		}
+		stack = make([]byte, len(stack)*2)
+	}
+	buf.Write(stack)
+	if len(stack) > 0 && stack[len(stack)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	fmt.Fprintf(&buf, "======== runtime dump end %s ========\n", now)
+	return buf.Bytes()
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build !unix
+
+package logger
+
+import "os"
+
+var dumpSignals, reopenSignals, debugSignals []os.Signal
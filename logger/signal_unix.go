@@ -0,0 +1,21 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	dumpSignals   = []os.Signal{syscall.SIGUSR1}
+	reopenSignals = []os.Signal{syscall.SIGHUP}
+	debugSignals  = []os.Signal{syscall.SIGUSR2}
+)
@@ -13,6 +13,9 @@ import (
 	"runtime"
 )
 
+// _NOCALLER is a call depth that leaves the caller out, for entries that are not logged by user code.
+const _NOCALLER = -1 << 20
+
 type callStack struct {
 	stack []*callerInfo
 }
@@ -0,0 +1,98 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+)
+
+// std_calldepth is the depth of the caller of log.Printf and friends, seen from stdWriter.Write.
+const std_calldepth = 4
+
+// stdWriter is the io.Writer behind the standard library loggers. Each Write call is one log entry.
+type stdWriter struct {
+	logging *Logging
+	level   LEVELTYPE
+	infer   bool
+}
+
+func (w *stdWriter) Write(bs []byte) (int, error) {
+	level, msg := w.level, string(bytes.TrimRight(bs, "\r\n"))
+	if w.infer {
+		if l, rest, ok := inferLevel(msg); ok {
+			level, msg = l, rest
+		}
+	}
+	w.logging.printlnctx(nil, nil, level, std_calldepth, msg)
+	return len(bs), nil
+}
+
+// NewStdLogger returns a *log.Logger whose output is logged to l at the given level,
+// going through the formatting, level and console rules of l.
+//
+// 返回标准库*log.Logger，其输出以指定级别写入l
+//
+// e.g.
+//
+//	server := &http.Server{ErrorLog: logger.NewStdLogger(log, logger.LEVEL_ERROR)}
+func NewStdLogger(l *Logging, level LEVELTYPE) *log.Logger {
+	return log.New(&stdWriter{logging: l, level: level}, "", 0)
+}
+
+// RedirectStdLog sends the output of the standard library's default logger to l.
+// The level of each entry is inferred from a leading prefix such as "[ERROR]", "WARN:" or "debug:",
+// which is removed from the message; entries without a recognized prefix are logged at level.
+// The returned function restores the previous output, flags and prefix of the default logger.
+//
+// 将标准库默认logger的输出重定向到l，根据"[ERROR]"、"WARN:"等前缀推断日志级别并去除前缀，
+// 无法识别前缀的日志以level级别记录。返回的函数用于恢复标准库logger原有的设置。
+func RedirectStdLog(l *Logging, level LEVELTYPE) (restore func()) {
+	w, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(&stdWriter{logging: l, level: level, infer: true})
+	log.SetFlags(0)
+	log.SetPrefix("")
+	return func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// inferLevel recognizes a level prefix like "[WARN]" or "warn:" at the start of msg.
+func inferLevel(msg string) (LEVELTYPE, string, bool) {
+	s := strings.TrimLeft(msg, " \t")
+	var name, rest string
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return LEVEL_ALL, msg, false
+		}
+		name, rest = s[1:i], s[i+1:]
+	} else {
+		i := strings.IndexByte(s, ':')
+		if i <= 0 || strings.ContainsAny(s[:i], " \t") {
+			return LEVEL_ALL, msg, false
+		}
+		name, rest = s[:i], s[i+1:]
+	}
+	if level, ok := parseLevel(name); ok && level > LEVEL_ALL && level < LEVEL_OFF && !isNumeric(name) {
+		return level, strings.TrimLeft(rest, ": \t"), true
+	}
+	return LEVEL_ALL, msg, false
+}
+
+func isNumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TraceContext holds the W3C trace context of a request, as carried by the traceparent header.
+// TraceContext 保存W3C trace context信息，即traceparent请求头的内容。
+type TraceContext struct {
+	TraceID string // 32 lowercase hex characters / 32位小写十六进制字符
+	SpanID  string // 16 lowercase hex characters / 16位小写十六进制字符
+	Flags   string // 2 lowercase hex characters, "01" means sampled / 2位小写十六进制字符，"01"表示采样
+}
+
+// String returns the traceparent header value of tc.
+func (tc TraceContext) String() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+type traceKey struct{}
+
+var errTraceparent = errors.New("invalid traceparent")
+
+func init() {
+	RegisterContextExtractor("trace_id", func(ctx context.Context) (any, bool) {
+		tc, ok := TraceFromContext(ctx)
+		return tc.TraceID, ok
+	})
+	RegisterContextExtractor("span_id", func(ctx context.Context) (any, bool) {
+		tc, ok := TraceFromContext(ctx)
+		return tc.SpanID, ok
+	})
+}
+
+// ParseTraceparent parses a W3C traceparent header, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+//
+// 解析W3C traceparent请求头
+func ParseTraceparent(header string) (tc TraceContext, err error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return tc, errTraceparent
+	}
+	if !isHex(parts[1], 32) || parts[1] == strings.Repeat("0", 32) || !isHex(parts[2], 16) || parts[2] == strings.Repeat("0", 16) || !isHex(parts[3], 2) {
+		return tc, errTraceparent
+	}
+	return TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: parts[3]}, nil
+}
+
+// ContextWithTraceparent parses header and returns a copy of ctx carrying the trace context.
+// If header is not a valid traceparent, ctx is returned unchanged along with the error.
+//
+// 解析traceparent请求头并将trace context保存到ctx中
+func ContextWithTraceparent(ctx context.Context, header string) (context.Context, error) {
+	tc, err := ParseTraceparent(header)
+	if err != nil {
+		return ctx, err
+	}
+	return ContextWithTrace(ctx, tc), nil
+}
+
+// ContextWithTrace returns a copy of ctx carrying tc. The *Context logging methods attach its
+// TraceID and SpanID as the fields trace_id and span_id.
+//
+// 返回携带tc的ctx副本，通过*Context方法打印日志时附加trace_id与span_id字段
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context carried by ctx.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// NewTraceContext returns a trace context with random trace and span IDs, flagged as sampled.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+}
+
+// TraceMiddleware returns an HTTP middleware that stores the trace context of the traceparent
+// request header in the request context. Requests without a valid traceparent get a new random
+// trace context, so that their log entries still correlate.
+//
+// TraceMiddleware 返回HTTP中间件，将traceparent请求头解析后保存到请求的context中，
+// 请求头不存在或无效时生成新的trace context
+//
+// e.g.
+//
+//	http.ListenAndServe(":8080", logger.TraceMiddleware(mux))
+//	// in the handler:
+//	logger.InfoContext(r.Context(), "this is a info message")
+func TraceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, err := ParseTraceparent(r.Header.Get("traceparent"))
+		if err != nil {
+			tc = NewTraceContext()
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithTrace(r.Context(), tc)))
+	})
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	bs := make([]byte, n)
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewViewHandler returns an http.Handler for watching the records of a Logging instance from a browser.
+// A plain GET serves a minimal HTML page; a request with "stream=1" or "Accept: text/event-stream"
+// receives the records as a Server-Sent Events stream. Both accept the query-string filters
+// "level" (minimum level, e.g. "warn" or "3") and "q" (substring of the message).
+//
+// NewViewHandler 返回通过浏览器查看日志的http.Handler，普通GET请求返回HTML页面，
+// 带stream=1参数或Accept为text/event-stream的请求以SSE方式推送日志，支持level与q过滤参数。
+//
+// e.g.
+//
+//	http.Handle("/logs", logger.NewViewHandler(logger.GetStaticLogger()))
+func NewViewHandler(l *Logging) http.Handler {
+	return &viewHandler{logging: l}
+}
+
+type viewHandler struct {
+	logging *Logging
+}
+
+type viewEvent struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	File    string    `json:"file"`
+	Line    int       `json:"line"`
+	Message string    `json:"message"`
+	Fields  Fields    `json:"fields,omitempty"`
+	Raw     string    `json:"raw"`
+}
+
+type viewSink struct {
+	level    LEVELTYPE
+	contains string
+	ch       chan *Record
+}
+
+func (s *viewSink) WriteRecord(r *Record) {
+	if r.Level < s.level || (s.contains != "" && !strings.Contains(r.Message, s.contains)) {
+		return
+	}
+	select {
+	case s.ch <- r:
+	default:
+	}
+}
+
+func (h *viewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("stream") == "" && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(viewPage))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sink := &viewSink{contains: r.URL.Query().Get("q"), ch: make(chan *Record, 1<<10)}
+	if level, ok := parseLevel(r.URL.Query().Get("level")); ok {
+		sink.level = level
+	}
+	h.logging.AddSink(sink)
+	defer h.logging.RemoveSink(sink)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case rec := <-sink.ch:
+			bs, err := json.Marshal(&viewEvent{Time: rec.Time, Level: strings.Trim(string(getlevelname(rec.Level)), "[]"), File: rec.File, Line: rec.Line, Message: rec.Message, Fields: rec.Fields, Raw: string(rec.Raw)})
+			if err != nil {
+				continue
+			}
+			if _, err = w.Write(append(append([]byte("data: "), bs...), '\n', '\n')); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// parseLevel parses a level name such as "debug", "WARN" or "[ERROR]", or its numeric value.
+func parseLevel(s string) (LEVELTYPE, bool) {
+	s = strings.ToUpper(strings.Trim(strings.TrimSpace(s), "[]"))
+	switch s {
+	case "ALL":
+		return LEVEL_ALL, true
+	case "DEBUG":
+		return LEVEL_DEBUG, true
+	case "INFO":
+		return LEVEL_INFO, true
+	case "WARN", "WARNING":
+		return LEVEL_WARN, true
+	case "ERROR":
+		return LEVEL_ERROR, true
+	case "FATAL":
+		return LEVEL_FATAL, true
+	case "OFF":
+		return LEVEL_OFF, true
+	}
+	if i, err := strconv.Atoi(s); err == nil && i >= int(LEVEL_ALL) && i <= int(LEVEL_OFF) {
+		return LEVELTYPE(i), true
+	}
+	return LEVEL_ALL, false
+}
+
+const viewPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-logger</title>
+<style>
+body{margin:0;font:13px monospace;background:#1e1e1e;color:#ddd}
+#bar{position:sticky;top:0;padding:6px;background:#333}
+#log{margin:0;padding:6px;white-space:pre-wrap}
+.DEBUG{color:#6cf}.INFO{color:#8c8}.WARN{color:#fc6}.ERROR{color:#f66}.FATAL{color:#fff;background:#a00}
+</style>
+</head>
+<body>
+<div id="bar">
+level <select id="level"><option>ALL</option><option>DEBUG</option><option>INFO</option><option>WARN</option><option>ERROR</option><option>FATAL</option></select>
+filter <input id="q">
+<button id="apply">apply</button>
+<button id="clear">clear</button>
+<label><input id="follow" type="checkbox" checked>follow</label>
+</div>
+<pre id="log"></pre>
+<script>
+var es, log = document.getElementById("log");
+function connect() {
+	if (es) es.close();
+	var p = new URLSearchParams(location.search);
+	p.set("stream", "1");
+	p.set("level", document.getElementById("level").value);
+	p.set("q", document.getElementById("q").value);
+	es = new EventSource(location.pathname + "?" + p.toString());
+	es.onmessage = function(e) {
+		var r = JSON.parse(e.data), s = document.createElement("span");
+		s.className = r.level;
+		s.textContent = r.raw;
+		log.appendChild(s);
+		while (log.childNodes.length > 5000) log.removeChild(log.firstChild);
+		if (document.getElementById("follow").checked) window.scrollTo(0, document.body.scrollHeight);
+	};
+}
+(function() {
+	var p = new URLSearchParams(location.search);
+	if (p.get("level")) document.getElementById("level").value = p.get("level").toUpperCase();
+	if (p.get("q")) document.getElementById("q").value = p.get("q");
+})();
+document.getElementById("apply").onclick = connect;
+document.getElementById("clear").onclick = function() { log.textContent = ""; };
+connect();
+</script>
+</body>
+</html>
+`
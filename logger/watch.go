@@ -0,0 +1,91 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"os"
+	"time"
+)
+
+type _FILEEVENT int8
+
+const (
+	// EVENT_FILE_MOVED the log file was moved or replaced by another file, and has been reopened
+	// 日志文件被移动或替换，已重新打开
+	EVENT_FILE_MOVED _FILEEVENT = 1
+
+	// EVENT_FILE_DELETED the log file was deleted, and has been recreated
+	// 日志文件被删除，已重新创建
+	EVENT_FILE_DELETED _FILEEVENT = 2
+)
+
+// FileEvent describes something that happened to a log file outside of the normal write path.
+// FileEvent 描述日志文件在正常写入之外发生的事件
+type FileEvent struct {
+	Type _FILEEVENT // Event type / 事件类型
+	Path string     // Path of the log file concerned / 相关的日志文件路径
+	Err  error      // Error encountered while handling the event, if any / 处理事件时发生的错误
+}
+
+func (t *Logging) fireEvent(ev *FileEvent) {
+	if t.onFileEvent != nil {
+		defer recoverable(nil)
+		t.onFileEvent(ev)
+	} else if ev.Err != nil {
+		fprintln(nil, default_format, LEVEL_ERROR, 0, 1, nil, nil, ev.Err.Error())
+	}
+}
+
+// watchFile starts checking every interval whether the open log file is still the one at its path,
+// stopping the previous check if any. An interval <= 0 only stops it.
+func (t *Logging) watchFile(interval time.Duration) {
+	if t.watchStop != nil {
+		close(t.watchStop)
+		t.watchStop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	t.watchStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.checkFile()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// checkFile reopens the log file when the path no longer refers to the open file, because it was moved or deleted.
+func (t *Logging) checkFile() {
+	defer recoverable(nil)
+	var typ _FILEEVENT
+	t._rwLock.RLock()
+	if !t._isFileWell || t._filehandler.file == nil {
+		t._rwLock.RUnlock()
+		return
+	}
+	path := t._filehandler.path()
+	if open, err := t._filehandler.file.Stat(); err == nil {
+		if fi, err := os.Stat(path); os.IsNotExist(err) {
+			typ = EVENT_FILE_DELETED
+		} else if err == nil && !os.SameFile(open, fi) {
+			typ = EVENT_FILE_MOVED
+		}
+	}
+	t._rwLock.RUnlock()
+	if typ != 0 {
+		t.fireEvent(&FileEvent{Type: typ, Path: path, Err: t.Reopen()})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2014, donnie <donnie4w@gmail.com>
+// All rights reserved.
+// Use of t source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// github.com/donnie4w/go-logger
+
+package logger
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// _WRITER_MAXLINE is the size at which an incomplete line of a levelWriter is logged without waiting for its newline.
+const _WRITER_MAXLINE = 64 << 10
+
+// _RELAY_PACKAGES are the packages that pass bytes on to a writer for their caller, such as fmt.Fprint or io.Copy.
+var _RELAY_PACKAGES = []string{"fmt.", "io.", "bufio.", "log.", "os.", "os/exec.", "github.com/donnie4w/go-logger/logger."}
+
+// levelWriter splits the bytes written to it into lines and logs each line at a fixed level.
+type levelWriter struct {
+	mu      sync.Mutex
+	logging *Logging
+	level   LEVELTYPE
+	prefix  string
+	buf     []byte
+}
+
+// Writer returns an io.WriteCloser on the default logging instance. See Logging.Writer.
+func Writer(level LEVELTYPE) io.WriteCloser {
+	return static_lo.Writer(level)
+}
+
+// Writer returns an io.WriteCloser that splits the incoming bytes into lines and logs each line at the given level,
+// applying the format, level and console settings of the Logging instance. An incomplete trailing line is kept until
+// its newline arrives, it reaches 64 KiB or the writer is closed. Unlike Write, it never bypasses formatting.
+// The caller reported is the code that called Write, through fmt.Fprint, io.Copy and the like; bytes copied by
+// a goroutine of such a package, e.g. the output of an exec.Cmd, are logged without a caller.
+//
+// 返回io.WriteCloser，将写入的数据按行切分，并以指定级别逐行打印。不完整的末行在收到换行符、达到64KiB或Close时打印。
+// 日志的调用位置为Write的调用者（跳过fmt.Fprint、io.Copy等转发），由这些包的协程转发的数据不记录调用位置。
+//
+// e.g.
+//
+//	cmd := exec.Command("make")
+//	cmd.Stdout, cmd.Stderr = log.Writer(logger.LEVEL_INFO), log.Writer(logger.LEVEL_ERROR)
+func (t *Logging) Writer(level LEVELTYPE) io.WriteCloser {
+	return &levelWriter{logging: t, level: level}
+}
+
+func (w *levelWriter) Write(bs []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, calldepth := len(bs), writerCalldepth()
+	for len(bs) > 0 {
+		i := bytes.IndexByte(bs, '\n')
+		if i < 0 {
+			if w.buf = append(w.buf, bs...); len(w.buf) >= _WRITER_MAXLINE {
+				w.print(calldepth, w.buf)
+			}
+			break
+		}
+		line := bs[:i]
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, line...)
+			line = w.buf
+		}
+		w.print(calldepth, bytes.TrimSuffix(line, []byte{'\r'}))
+		bs = bs[i+1:]
+	}
+	return n, nil
+}
+
+// print logs line with the prefix and empties the buffer.
+func (w *levelWriter) print(calldepth int, line []byte) {
+	if calldepth != _NOCALLER {
+		calldepth++
+	}
+	w.logging.printlnctx(nil, nil, w.level, calldepth, w.prefix+string(line))
+	w.buf = w.buf[:0]
+}
+
+// writerCalldepth returns the calldepth, relative to the method of levelWriter calling it, of the first caller outside
+// the packages relaying writes, or _NOCALLER when the bytes are relayed by a goroutine of such a package.
+func writerCalldepth() int {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for calldepth := 2; ; calldepth++ {
+		f, more := frames.Next()
+		if f.Function == "" || strings.HasPrefix(f.Function, "runtime.") {
+			return _NOCALLER
+		}
+		if !relayed(f.Function) {
+			return calldepth
+		}
+		if !more {
+			return _NOCALLER
+		}
+	}
+}
+
+func relayed(function string) bool {
+	for _, p := range _RELAY_PACKAGES {
+		if strings.HasPrefix(function, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close logs the pending incomplete line, if any.
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.print(writerCalldepth(), bytes.TrimSuffix(w.buf, []byte{'\r'}))
+	}
+	return nil
+}
@@ -0,0 +1,1126 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/donnie4w/go-logger/logger"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer(t *testing.T) {
+	log, rb := newRingLogger(10)
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		log.Debug("this is a debug message:" + strconv.Itoa(i))
+		log.Error("this is a error message:" + strconv.Itoa(i))
+	}
+	if rb.Len() != 10 {
+		t.Fatalf("expected 10 records, got %d", rb.Len())
+	}
+	rs := rb.Query(&logger.Query{Level: logger.LEVEL_ERROR, Since: start, File: "0_29_0_test.go", Contains: "19"})
+	if len(rs) != 1 || rs[0].Message != "this is a error message:19" {
+		t.Fatalf("unexpected query result: %v", rs)
+	}
+	for _, line := range rb.Lines(&logger.Query{Limit: 2}) {
+		t.Log(string(line))
+	}
+	log.RemoveSink(rb)
+	log.Info("not recorded")
+	if rb.Len() != 10 {
+		t.Fatalf("expected 10 records, got %d", rb.Len())
+	}
+}
+
+func TestViewHandler(t *testing.T) {
+	log := logger.NewLogger()
+	log.SetConsole(false)
+	server := httptest.NewServer(logger.NewViewHandler(log))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?stream=1&level=warn&q=viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	log.Info("this is a info message for viewer")
+	log.Warn("this is a warn message")
+	log.Warn("this is a warn message for viewer")
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "data: ") || !strings.Contains(line, "this is a warn message for viewer") {
+		t.Fatalf("unexpected event: %s", line)
+	}
+	t.Log(line)
+}
+
+func TestFlightRecorder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "flight.log")
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{Level: logger.LEVEL_INFO, FlightRecorder: 3, FileOption: &logger.FileSizeMode{Filename: filename, Maxsize: 1 << 20}})
+	rb := logger.NewRingBuffer(10, 0)
+	log.AddSink(rb)
+	for i := 0; i < 5; i++ {
+		log.Debug("this is a debug message:" + strconv.Itoa(i))
+	}
+	log.Info("this is a info message")
+	log.Error("this is a error message")
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], "[FLIGHT][DEBUG]") || !strings.HasSuffix(lines[1], "debug message:2") || !strings.Contains(lines[4], "[ERROR]") {
+		t.Fatalf("unexpected log file:\n%s", bs)
+	}
+	rs := rb.Query(nil)
+	if len(rs) != 5 || rs[1].Level != logger.LEVEL_DEBUG || !strings.HasPrefix(string(rs[1].Raw), "[FLIGHT]") || rs[1].Message != "this is a debug message:2" {
+		t.Fatalf("unexpected records: %v", rb.Lines(nil))
+	}
+	t.Log("\n" + string(bs))
+}
+
+type requestIDKey struct{}
+
+func TestContextExtractor(t *testing.T) {
+	logger.RegisterContextExtractor("request_id", func(ctx context.Context) (any, bool) {
+		v, ok := ctx.Value(requestIDKey{}).(string)
+		return v, ok
+	})
+	defer logger.RegisterContextExtractor("request_id", nil)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+
+	log, rb := newRingLogger(10)
+	log.InfoContext(ctx, "this is a info message")
+	log.SetFormatter("{level} {field:request_id} {message}\n")
+	log.WarnContext(ctx, "this is a warn message")
+	log.Error("this is a error message")
+
+	lines := rb.Lines(nil)
+	if !strings.HasSuffix(string(lines[0]), "this is a info message request_id=req-1\n") {
+		t.Fatalf("unexpected line: %s", lines[0])
+	}
+	if string(lines[1]) != "[WARN] req-1 this is a warn message\n" {
+		t.Fatalf("unexpected line: %s", lines[1])
+	}
+	if string(lines[2]) != "[ERROR]  this is a error message\n" {
+		t.Fatalf("unexpected line: %s", lines[2])
+	}
+	if v, ok := rb.Query(nil)[0].Fields.Get("request_id"); !ok || v != "req-1" {
+		t.Fatalf("unexpected fields: %v", rb.Query(nil)[0].Fields)
+	}
+	logger.SetConsole(true)
+	logger.InfoContext(ctx, "this is a info message")
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	log, rb := newRingLogger(10)
+	handler := logger.TraceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.InfoContext(r.Context(), "this is a info message")
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	rec := rb.Query(nil)[0]
+	if !strings.HasSuffix(string(rec.Raw), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n") {
+		t.Fatalf("unexpected line: %s", rec.Raw)
+	}
+	bs, _ := json.Marshal(rec)
+	if !strings.Contains(string(bs), `"fields":{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}`) {
+		t.Fatalf("unexpected json: %s", bs)
+	}
+	if _, err := logger.ParseTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01"); err == nil {
+		t.Fatal("expected invalid traceparent")
+	}
+	t.Log(string(bs))
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	log, rb := newRingLogger(10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "fail", 500) })
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("this is a panic message") })
+	log.SetFormat(logger.FORMAT_LEVELFLAG | logger.FORMAT_SHORTFILENAME)
+	handler := logger.AccessLogMiddleware(log, &logger.AccessLogOption{Format: logger.ACCESS_COMBINED, Sample2xx: 2})(mux)
+	for _, path := range []string{"/ok", "/ok", "/ok", "/fail", "/missing", "/panic"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("User-Agent", "go-test")
+		func() {
+			defer func() {
+				if p := recover(); (p != nil) != (path == "/panic") {
+					t.Fatalf("unexpected panic of %s: %v", path, p)
+				}
+			}()
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+		}()
+	}
+	rs := rb.Query(nil)
+	if len(rs) != 5 {
+		t.Fatalf("expected 5 records, got %d", len(rs))
+	}
+	if rs[0].Level != logger.LEVEL_INFO || !strings.HasSuffix(rs[0].Message, `"GET /ok HTTP/1.1" 200 5 "-" "go-test"`) {
+		t.Fatalf("unexpected record: %s", rs[0].Message)
+	}
+	if rs[2].Level != logger.LEVEL_ERROR || rs[3].Level != logger.LEVEL_WARN {
+		t.Fatalf("unexpected levels: %d %d", rs[2].Level, rs[3].Level)
+	}
+	if rs[4].Level != logger.LEVEL_ERROR || !strings.Contains(rs[4].Message, `"GET /panic HTTP/1.1" 500 `) {
+		t.Fatalf("unexpected record: %d %s", rs[4].Level, rs[4].Message)
+	}
+	if rs[0].File != "" || string(rs[0].Raw) != "[INFO] "+rs[0].Message+"\n" {
+		t.Fatalf("unexpected caller: %s %q", rs[0].File, rs[0].Raw)
+	}
+	for _, line := range rb.Lines(nil) {
+		t.Log(string(line))
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	log, rb := newRingLogger(10)
+	logger.NewStdLogger(log, logger.LEVEL_WARN).Printf("this is a %s message", "std")
+	restore := logger.RedirectStdLog(log, logger.LEVEL_INFO)
+	stdlog.Println("[ERROR] this is a error message")
+	stdlog.Print("debug: this is a debug message")
+	stdlog.Print("this is a info message")
+	restore()
+
+	rs := rb.Query(nil)
+	if len(rs) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(rs))
+	}
+	for i, level := range []logger.LEVELTYPE{logger.LEVEL_WARN, logger.LEVEL_ERROR, logger.LEVEL_DEBUG, logger.LEVEL_INFO} {
+		if rs[i].Level != level || !strings.HasPrefix(rs[i].Message, "this is a") || !strings.HasSuffix(rs[i].File, "0_29_0_test.go") {
+			t.Fatalf("unexpected record: %d %s %s", rs[i].Level, rs[i].Message, rs[i].File)
+		}
+	}
+	for _, line := range rb.Lines(nil) {
+		t.Log(string(line))
+	}
+}
+
+func TestLevelWriter(t *testing.T) {
+	log, rb := newRingLogger(10)
+	w := log.Writer(logger.LEVEL_WARN)
+	fmt.Fprint(w, "this is a warn message:1\nthis is a warn ")
+	fmt.Fprint(w, "message:2\r\nthis is a warn message:3")
+	if rb.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", rb.Len())
+	}
+	w.Close()
+	for i, r := range rb.Query(nil) {
+		if r.Level != logger.LEVEL_WARN || r.Message != "this is a warn message:"+strconv.Itoa(i+1) || !strings.HasSuffix(r.File, "0_29_0_test.go") {
+			t.Fatalf("unexpected record: %d %q %s", r.Level, r.Message, r.File)
+		}
+	}
+
+	// a line without newline is not buffered without limit
+	log, rb = newRingLogger(10)
+	w = log.Writer(logger.LEVEL_WARN)
+	w.Write([]byte(strings.Repeat("a", 100<<10)))
+	if rs := rb.Query(nil); len(rs) != 1 || len(rs[0].Message) != 100<<10 {
+		t.Fatalf("the long line was not logged: %d records", len(rs))
+	}
+}
+
+func TestCaptureStdio(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("capturing stdio is only supported on linux")
+	}
+	log := logger.NewLogger()
+	rb := logger.NewRingBuffer(10, 0)
+	log.AddSink(rb)
+	if err := log.CaptureStdio(logger.LEVEL_INFO, logger.LEVEL_ERROR); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("this is a stdout message")
+	fmt.Fprint(os.Stderr, "this is a stderr message")
+	if err := logger.ReleaseStdio(); err != nil {
+		t.Fatal(err)
+	}
+	if rs := rb.Query(&logger.Query{Level: logger.LEVEL_INFO, Contains: "[stdout] this is a stdout message"}); len(rs) != 1 || rs[0].Level != logger.LEVEL_INFO {
+		t.Fatalf("unexpected records: %v", rb.Lines(nil))
+	}
+	if rs := rb.Query(&logger.Query{Level: logger.LEVEL_ERROR, Contains: "[stderr] this is a stderr message"}); len(rs) != 1 {
+		t.Fatalf("unexpected records: %v", rb.Lines(nil))
+	}
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	log, rb := newRingLogger(10)
+	log.Go(func() {
+		panic("this is a panic message")
+	})
+	waitFor(t, time.Second, func() bool { return rb.Len() > 0 })
+	rs := rb.Query(&logger.Query{Level: logger.LEVEL_FATAL, Contains: "panic: this is a panic message"})
+	if len(rs) != 1 || !strings.Contains(rs[0].Message, "goroutine") {
+		t.Fatalf("unexpected records: %v", rb.Lines(nil))
+	}
+}
+
+func TestCrashOutput(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crash.log")
+	if os.Getenv("LOGGER_CRASH_FILE") != "" {
+		logger.SetOption(&logger.Option{CrashOutput: true, FileOption: &logger.FileSizeMode{Filename: os.Getenv("LOGGER_CRASH_FILE"), Maxsize: 1 << 20}})
+		panic("this is a crash message")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashOutput$")
+	cmd.Env = append(os.Environ(), "LOGGER_CRASH_FILE="+filename)
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected the process to crash")
+	}
+	bs, _ := os.ReadFile(filename)
+	if !strings.Contains(string(bs), "panic: this is a crash message") {
+		t.Fatalf("unexpected log file:\n%s", bs)
+	}
+}
+
+func TestDumpOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals is not supported on windows")
+	}
+	dir := t.TempDir()
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{FileOption: &logger.FileSizeMode{Filename: filepath.Join(dir, "dump.log"), Maxsize: 1 << 10}})
+	log.Info("this is a info message")
+	stop := log.DumpOnSignal(os.Interrupt)
+	defer stop()
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(os.Interrupt)
+	for i := 0; i < 100; i++ {
+		if bs, _ := os.ReadFile(filepath.Join(dir, "dump.log")); strings.Contains(string(bs), "runtime dump end") {
+			if !strings.Contains(string(bs), "\n======== runtime dump begin") || !strings.Contains(string(bs), "goroutine ") {
+				t.Fatalf("unexpected dump:\n%s", bs)
+			}
+			log.Info("this is a info message after the dump")
+			if bs, err := os.ReadFile(filepath.Join(dir, "dump_1.log")); err != nil || !strings.Contains(string(bs), "runtime dump end") {
+				t.Fatalf("expected the dump to be rotated: %v", err)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("runtime dump not found")
+}
+
+func TestReopenOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals is not supported on windows")
+	}
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "reopen.log")
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{Level: logger.LEVEL_INFO, FileOption: &logger.FileSizeMode{Filename: filename, Maxsize: 1 << 20}})
+	log.Info("this is a info message:1")
+	os.Rename(filename, filename+".1")
+	log.Info("this is a info message:2")
+
+	stopReopen := log.ReopenOnSignal(os.Interrupt)
+	stopDebug := log.DebugOnSignal(100*time.Millisecond, os.Interrupt)
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(os.Interrupt)
+	for i := 0; i < 100 && !isFileExist(filename); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	log.Debug("this is a debug message:3")
+	time.Sleep(200 * time.Millisecond)
+	log.Debug("this is a debug message:4")
+	stopReopen()
+	stopDebug()
+
+	bs, _ := os.ReadFile(filename + ".1")
+	if strings.Count(string(bs), "\n") != 2 {
+		t.Fatalf("unexpected rotated file:\n%s", bs)
+	}
+	bs, _ = os.ReadFile(filename)
+	if !strings.Contains(string(bs), "debug message:3") || strings.Contains(string(bs), "debug message:4") {
+		t.Fatalf("unexpected log file:\n%s", bs)
+	}
+}
+
+func TestReopenKeepsConfigError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "reopen.log")
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{Console: false, FileOption: &logger.FileTimeMode{Filename: filename, Cronexpr: "not a cron expression"}})
+	if err := log.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("this is a info message")
+	log.Close()
+	if bs, _ := os.ReadFile(filename); len(bs) != 0 {
+		t.Fatalf("logged under an invalid configuration:\n%s", bs)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "watch.log")
+	events := make(chan *logger.FileEvent, 4)
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{WatchInterval: 20 * time.Millisecond, OnFileEvent: func(ev *logger.FileEvent) { events <- ev }, FileOption: &logger.FileSizeMode{Filename: filename, Maxsize: 1 << 20}})
+	defer log.Close()
+	log.Info("this is a info message:1")
+	os.Remove(filename)
+	if ev := <-events; ev.Type != logger.EVENT_FILE_DELETED || ev.Err != nil {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	log.Info("this is a info message:2")
+	os.Link(filename, filename+".1")
+	os.WriteFile(filename+".tmp", nil, 0644)
+	os.Rename(filename+".tmp", filename)
+	if ev := <-events; ev.Type != logger.EVENT_FILE_MOVED || ev.Err != nil {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	log.Info("this is a info message:3")
+	for i, name := range []string{filename + ".1", filename} {
+		if bs, _ := os.ReadFile(name); !strings.HasSuffix(string(bs), "this is a info message:"+strconv.Itoa(i+2)+"\n") {
+			t.Fatalf("unexpected log file %s:\n%s", name, bs)
+		}
+	}
+}
+
+func TestCopyTruncate(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "copytruncate.log")
+	log := newFileLogger(&logger.FileSizeMode{Filename: filename, Maxsize: 1 << 10, Rotatemode: logger.ROTATE_COPYTRUNCATE})
+	defer log.Close()
+	before, _ := os.Stat(filename)
+	writeLines(log, 50)
+	after, _ := os.Stat(filename)
+	if !os.SameFile(before, after) {
+		t.Fatal("the active file was replaced")
+	}
+	entries, _ := os.ReadDir(dir)
+	var total int64
+	for _, e := range entries {
+		fi, _ := e.Info()
+		total += fi.Size()
+	}
+	if len(entries) < 5 || total != 50*100 {
+		t.Fatalf("unexpected files: %d, total size %d", len(entries), total)
+	}
+}
+
+// wrappedOption only promotes the methods of FileOption, as options written for earlier versions do.
+type wrappedOption struct {
+	logger.FileOption
+	dir string
+}
+
+func (o wrappedOption) FilePath() string {
+	return filepath.Join(o.dir, o.FileOption.FilePath())
+}
+
+func TestPlainFileOption(t *testing.T) {
+	dir := t.TempDir()
+	option := &logger.FileSizeMode{Filename: "wrapped.log", Maxsize: 1 << 10, Rotatemode: logger.ROTATE_COPYTRUNCATE, Backupname: "{name}-{seq}{ext}"}
+	log := newFileLogger(wrappedOption{option, dir})
+	writeLines(log, 15)
+	log.Close()
+	if names := strings.Join(fileNames(dir), ","); names != "wrapped.log,wrapped_1.log" {
+		t.Fatalf("unexpected files: %s", names)
+	}
+}
+
+func TestRotateLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "link.log"), Maxsize: 1 << 10, Rotatemode: logger.ROTATE_LINK, Linkname: "current"})
+	writeLines(log, 50)
+	log.Info("this is the last message")
+	log.Close()
+
+	target, err := os.Readlink(filepath.Join(dir, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().Format("2006-01-02")
+	if target != "link-"+day+".4.log" {
+		t.Fatalf("unexpected link target: %s", target)
+	}
+	if bs, _ := os.ReadFile(filepath.Join(dir, "current")); !strings.HasSuffix(string(bs), "this is the last message\n") {
+		t.Fatalf("unexpected active file:\n%s", bs)
+	}
+	names := fileNames(dir)
+	if strings.Join(names, ",") != "current,link-"+day+".1.log,link-"+day+".2.log,link-"+day+".3.log,link-"+day+".4.log,link-"+day+".log" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+}
+
+func TestBackupNameTemplate(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "tpl-2000-01-01.001.log")
+	os.WriteFile(old, []byte("old"), 0666)
+	os.Chtimes(old, time.Unix(946684800, 0), time.Unix(946684800, 0))
+	os.WriteFile(filepath.Join(dir, "tpl_1.log"), []byte("legacy"), 0666)
+
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "tpl.log"), Maxsize: 1 << 10, Maxbackup: 4, Backupname: "{name}-{date}.{seq}{ext}"})
+	writeLines(log, 50)
+	log.Close() // waits for the retention in the background
+
+	names := fileNames(dir)
+	day := time.Now().Format("2006-01-02")
+	if strings.Join(names, ",") != "tpl-"+day+".001.log,tpl-"+day+".002.log,tpl-"+day+".003.log,tpl-"+day+".004.log,tpl.log,tpl_1.log" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+}
+
+func TestRetentionByAgeAndSize(t *testing.T) {
+	dir := t.TempDir()
+	for i, days := range []int{1, 5, 10} {
+		name := filepath.Join(dir, fmt.Sprint("age_", i+1, ".log"))
+		os.WriteFile(name, []byte("backup"), 0666)
+		tm := time.Now().AddDate(0, 0, -days)
+		os.Chtimes(name, tm, tm)
+	}
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "age.log"), Maxsize: 1 << 20, Maxage: 3})
+	log.Close()
+	if !isFileExist(filepath.Join(dir, "age_1.log")) || isFileExist(filepath.Join(dir, "age_2.log")) || isFileExist(filepath.Join(dir, "age_3.log")) {
+		t.Fatal("backups older than Maxage were not removed at startup")
+	}
+
+	for i := 1; i <= 3; i++ {
+		name := filepath.Join(dir, fmt.Sprint("size_", i, ".log"))
+		os.WriteFile(name, make([]byte, 400), 0666)
+		tm := time.Now().Add(time.Duration(i-4) * time.Hour)
+		os.Chtimes(name, tm, tm)
+	}
+	log = newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "size.log"), Maxsize: 500, Maxtotalsize: 1100})
+	log.Info(strings.Repeat("a", 600))
+	log.Info("this is a info message")
+	log.Close()
+	if !isFileExist(filepath.Join(dir, "size_4.log")) || !isFileExist(filepath.Join(dir, "size_3.log")) || isFileExist(filepath.Join(dir, "size_2.log")) || isFileExist(filepath.Join(dir, "size_1.log")) {
+		t.Fatal("backups beyond Maxtotalsize were not removed at rotation")
+	}
+}
+
+func TestBackupOrderByName(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		name := filepath.Join(dir, fmt.Sprint("order_", i, ".log"))
+		os.WriteFile(name, []byte("backup"), 0666)
+		tm := time.Now().Add(-time.Duration(i) * time.Hour)
+		os.Chtimes(name, tm, tm)
+	}
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "order.log"), Maxsize: 500, Maxbackup: 2})
+	for i := 0; i < 5; i++ {
+		log.Info(strings.Repeat("a", 600))
+	}
+	log.Info("this is a info message")
+	log.Close()
+
+	names := fileNames(dir)
+	if strings.Join(names, ",") != "order.log,order_7.log,order_8.log" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+}
+
+func TestBackupOrderAfterManyRotations(t *testing.T) {
+	cases := map[string]logger.FileOption{
+		"size":  &logger.FileSizeMode{Maxsize: 8, Maxbackup: 2},
+		"mixed": &logger.FileMixedMode{Maxsize: 8, Maxbackup: 2, Timemode: logger.MODE_DAY},
+		"link":  &logger.FileSizeMode{Maxsize: 8, Maxbackup: 2, Rotatemode: logger.ROTATE_LINK},
+	}
+	for name, option := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			switch o := option.(type) {
+			case *logger.FileSizeMode:
+				o.Filename = filepath.Join(dir, "many.log")
+			case *logger.FileMixedMode:
+				o.Filename = filepath.Join(dir, "many.log")
+			}
+			for i := 1; i <= 20; i++ {
+				log := newFileLogger(option)
+				log.Infof("line %02d", i) // one line per file
+				log.Close()               // waits for the retention of each rotation
+			}
+
+			var lines []string
+			for _, e := range fileNames(dir) {
+				if fi, _ := os.Lstat(filepath.Join(dir, e)); fi.Mode().IsRegular() {
+					bs, _ := os.ReadFile(filepath.Join(dir, e))
+					lines = append(lines, strings.TrimSpace(string(bs)))
+				}
+			}
+			sort.Strings(lines)
+			if strings.Join(lines, ",") != "line 18,line 19,line 20" {
+				t.Fatalf("the newest lines were not kept: %v in %v", lines, fileNames(dir))
+			}
+		})
+	}
+}
+
+func TestArchiveDir(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "archive", "2000", "01", "01")
+	os.MkdirAll(old, 0777)
+	os.WriteFile(filepath.Join(old, "arc_9.log"), []byte("old"), 0666)
+
+	// several runs rotate well past Maxbackup, each run going on from the backups of the one before
+	for i := 0; i < 5; i++ {
+		log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "arc.log"), Maxsize: 1 << 10, Maxbackup: 3, Archivedir: "archive", Archivedated: true})
+		writeLines(log, 50)
+		log.Close()
+	}
+
+	if isFileExist(filepath.Join(dir, "archive", "2000")) {
+		t.Fatal("the oldest backup and its empty folders were not removed")
+	}
+	today := filepath.Join(dir, "archive", filepath.FromSlash(time.Now().Format("2006/01/02")))
+	if names := fileNames(today); strings.Join(names, ",") != "arc_20.log,arc_21.log,arc_22.log" {
+		t.Fatalf("unexpected backups: %v", names)
+	}
+	if names := fileNames(dir); len(names) != 2 {
+		t.Fatalf("unexpected files in the log directory: %v", names)
+	}
+}
+
+func TestArchiveDatedWithoutDir(t *testing.T) {
+	dir := t.TempDir()
+	// a folder of the user next to the log file, holding a name that looks like a backup
+	foreign := filepath.Join(dir, "2000", "01", "01")
+	os.MkdirAll(foreign, 0777)
+	os.WriteFile(filepath.Join(foreign, "arc_1.log"), []byte("not a backup"), 0666)
+
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "arc.log"), Maxsize: 1 << 10, Maxbackup: 1, Archivedated: true})
+	writeLines(log, 50)
+	log.Close()
+
+	if !isFileExist(filepath.Join(foreign, "arc_1.log")) {
+		t.Fatal("a file outside the backups was removed")
+	}
+	if names := strings.Join(fileNames(dir), ","); names != "2000,arc.log,arc_4.log" {
+		t.Fatalf("unexpected files: %s", names)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "rotate.log"), []byte("previous run\n"), 0666)
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{Format: logger.FORMAT_NANO, RotateOnStart: true, FileOption: &logger.FileTimeMode{Filename: filepath.Join(dir, "rotate.log"), Timemode: logger.MODE_DAY}})
+	defer log.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("the log file was not rotated on start: %v", entries)
+	}
+	log.Info("this is a info message")
+	bakfn, err := log.Rotate()
+	if err != nil || bakfn == "" {
+		t.Fatalf("Rotate: %q, %v", bakfn, err)
+	}
+	if filepath.Dir(bakfn) != dir {
+		t.Fatalf("Rotate did not return the path of the backup: %s", bakfn)
+	}
+	if bs, _ := os.ReadFile(bakfn); string(bs) != "this is a info message\n" {
+		t.Fatalf("unexpected backup %s: %q", bakfn, bs)
+	}
+	if bakfn, err = log.Rotate(); bakfn != "" || err != nil {
+		t.Fatalf("an empty log file was rotated: %q, %v", bakfn, err)
+	}
+	log.Info("this is the last message")
+	if bs, _ := os.ReadFile(filepath.Join(dir, "rotate.log")); string(bs) != "this is the last message\n" {
+		t.Fatalf("unexpected log file: %q", bs)
+	}
+}
+
+func TestRotateCompressed(t *testing.T) {
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "rotate.log"), Maxsize: 1 << 20, IsCompress: true})
+	log.Info("this is a info message")
+	bakfn, err := log.Rotate()
+	if err != nil || !strings.HasSuffix(bakfn, ".gz") {
+		t.Fatalf("Rotate did not return the compressed backup: %q, %v", bakfn, err)
+	}
+	log.Close() // waits for the compression
+	if ls := readGzipLines(t, bakfn); len(ls) != 1 || ls[0] != "this is a info message" {
+		t.Fatalf("unexpected backup %s: %q", bakfn, ls)
+	}
+}
+
+func TestScheduledRotation(t *testing.T) {
+	defer func() { logger.TIME_DEVIATION = 0 }()
+	cases := []struct {
+		name   string
+		option logger.FileOption
+		layout string
+	}{
+		{"minute", &logger.FileTimeMode{Timemode: logger.MODE_MINUTE(1)}, "200601021504"},
+		{"cron", &logger.FileTimeMode{Cronexpr: "* * * * *"}, "200601021504"},
+		{"offset", &logger.FileTimeMode{Timemode: logger.MODE_DAY}, "20060102"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Now()
+			boundary := now.Truncate(time.Minute).Add(time.Minute)
+			fo := c.option.(*logger.FileTimeMode)
+			fo.Filename = filepath.Join(dir, c.name+".log")
+			if c.name == "offset" {
+				logger.TIME_DEVIATION = 0
+				day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+				fo.Timeoffset = now.Add(1500 * time.Millisecond).Sub(day).Truncate(time.Second)
+				boundary = day.Add(fo.Timeoffset)
+			} else {
+				logger.TIME_DEVIATION = boundary.Add(-500 * time.Millisecond).Sub(now)
+			}
+			log := logger.NewLogger()
+			log.SetOption(&logger.Option{Format: logger.FORMAT_NANO, FileOption: fo})
+			defer log.Close()
+			log.Info("first")
+			time.Sleep(time.Until(boundary.Add(-logger.TIME_DEVIATION)) + 50*time.Millisecond) // until the boundary has passed
+			log.Info("second")
+
+			var period time.Time
+			if c.name == "offset" {
+				period = boundary.AddDate(0, 0, -1)
+			} else {
+				period = boundary.Add(-time.Minute)
+			}
+			bak := filepath.Join(dir, c.name+"_"+period.Format(c.layout)+".log")
+			if bs, err := os.ReadFile(bak); err != nil || string(bs) != "first\n" {
+				t.Fatalf("unexpected backup %s: %q, %v", bak, bs, err)
+			}
+		})
+	}
+}
+
+func TestScheduleAcrossDST(t *testing.T) {
+	// New York skips 02:00-03:00 on 2026-03-08, Santiago skips the midnight of 2026-09-06.
+	cases := []struct {
+		name   string
+		zone   string
+		option logger.FileTimeMode
+		writes [][4]int // month, day, hour and minute of each write, only the last one starts a new period
+	}{
+		{"cron", "America/New_York", logger.FileTimeMode{Cronexpr: "0 2 * * *"}, [][4]int{{3, 7, 12, 0}, {3, 8, 12, 0}, {3, 9, 12, 0}}},
+		{"cronhalf", "America/New_York", logger.FileTimeMode{Cronexpr: "30 2 * * *"}, [][4]int{{3, 7, 12, 0}, {3, 8, 12, 0}, {3, 9, 12, 0}}},
+		{"weekly", "America/New_York", logger.FileTimeMode{Cronexpr: "@weekly"}, [][4]int{{3, 7, 12, 0}, {3, 8, 12, 0}}},
+		{"sunday", "America/New_York", logger.FileTimeMode{Cronexpr: "0 0 * * 7"}, [][4]int{{3, 7, 12, 0}, {3, 8, 12, 0}}},
+		{"hour", "America/New_York", logger.FileTimeMode{Timemode: logger.MODE_HOUR}, [][4]int{{3, 8, 1, 30}, {3, 8, 1, 30}, {3, 8, 1, 30}, {3, 8, 1, 59}, {3, 8, 3, 10}}},
+		{"day", "America/Santiago", logger.FileTimeMode{Timemode: logger.MODE_DAY}, [][4]int{{9, 5, 23, 30}, {9, 5, 23, 30}, {9, 5, 23, 59}, {9, 6, 12, 0}}},
+		{"daily", "America/Santiago", logger.FileTimeMode{Cronexpr: "@daily"}, [][4]int{{9, 5, 12, 0}, {9, 6, 12, 0}, {9, 7, 12, 0}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			loc := useZone(t, c.zone)
+			dir := t.TempDir()
+			fo := c.option
+			fo.Filename = filepath.Join(dir, c.name+".log")
+			setClock(time.Date(2026, time.Month(c.writes[0][0]), c.writes[0][1], c.writes[0][2], c.writes[0][3], 0, 0, loc))
+			var log *logger.Logging
+			within(t, func() { log = newFileLogger(&fo) })
+			for i, w := range c.writes {
+				logAt(t, log, time.Date(2026, time.Month(w[0]), w[1], w[2], w[3], 0, 0, loc), fmt.Sprintf("line %d", i))
+			}
+			log.Close() // not deferred, it would wait for a write that hangs
+			if names := fileNames(dir); len(names) != 2 {
+				t.Fatalf("want the log file and one backup, got %v", names)
+			}
+			if bs, _ := os.ReadFile(fo.Filename); string(bs) != fmt.Sprintf("line %d\n", len(c.writes)-1) {
+				t.Fatalf("unexpected log file: %q", bs)
+			}
+		})
+	}
+}
+
+func TestWeeklyRotation(t *testing.T) {
+	useZone(t, "UTC")
+	cases := []struct {
+		name   string
+		mode   logger.FileOption
+		writes []time.Time // only the last one starts a new week
+		backup string
+	}{
+		{"monday", &logger.FileTimeMode{Timemode: logger.MODE_WEEK},
+			[]time.Time{time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 15, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 1, 0, 0, time.UTC)}, "monday_20260309.log"},
+		{"sunday", &logger.FileTimeMode{Timemode: logger.MODE_WEEK_FROM(time.Sunday)},
+			[]time.Time{time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 14, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 15, 0, 1, 0, 0, time.UTC)}, "sunday_20260308.log"},
+		{"friday", &logger.FileTimeMode{Timemode: logger.MODE_WEEK_FROM(time.Friday)},
+			[]time.Time{time.Date(2026, 3, 13, 0, 1, 0, 0, time.UTC), time.Date(2026, 3, 19, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 20, 0, 1, 0, 0, time.UTC)}, "friday_20260313.log"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c.mode.(*logger.FileTimeMode).Filename = filepath.Join(dir, c.name+".log")
+			setClock(c.writes[0])
+			log := newFileLogger(c.mode)
+			defer log.Close()
+			for i, w := range c.writes {
+				logAt(t, log, w, fmt.Sprintf("line %d", i))
+			}
+			if bs, err := os.ReadFile(filepath.Join(dir, c.backup)); err != nil || string(bs) != "line 0\nline 1\n" {
+				t.Fatalf("unexpected backup %s: %q, %v in %v", c.backup, bs, err, fileNames(dir))
+			}
+		})
+	}
+}
+
+func TestCronExpressions(t *testing.T) {
+	useZone(t, "UTC")
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		expr     string
+		from, to time.Time
+		rotated  bool
+	}{
+		{"*/15 * * * *", at(3, 2, 10, 5), at(3, 2, 10, 14), false},
+		{"*/15 * * * *", at(3, 2, 10, 5), at(3, 2, 10, 16), true},
+		{"0 8-18/2 * * MON-FRI", at(3, 13, 17, 0), at(3, 13, 18, 30), true},
+		{"0 8-18/2 * * MON-FRI", at(3, 13, 18, 30), at(3, 16, 7, 59), false},
+		{"0 8-18/2 * * MON-FRI", at(3, 13, 18, 30), at(3, 16, 8, 0), true},
+		{"0 0 1,15 * *", at(3, 2, 0, 0), at(3, 14, 23, 59), false},
+		{"0 0 1,15 * *", at(3, 14, 23, 59), at(3, 15, 0, 1), true},
+		{"0 12 * jan-feb *", at(3, 1, 0, 0), at(12, 31, 23, 59), false},
+		{"@hourly", at(3, 2, 10, 5), at(3, 2, 10, 59), false},
+		{"@hourly", at(3, 2, 10, 59), at(3, 2, 11, 0), true},
+		{"@monthly", at(3, 2, 0, 0), at(3, 31, 23, 59), false},
+		{"@monthly", at(3, 31, 23, 59), at(4, 1, 0, 0), true},
+		{"@yearly", at(1, 2, 0, 0), at(12, 31, 23, 59), false},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "cron.log")
+			setClock(c.from)
+			log := newFileLogger(&logger.FileTimeMode{Filename: filename, Cronexpr: c.expr})
+			defer log.Close()
+			logAt(t, log, c.from, "first")
+			logAt(t, log, c.to, "second")
+			if names := fileNames(dir); (len(names) == 2) != c.rotated {
+				t.Fatalf("%v to %v: rotated %v, got %v", c.from, c.to, c.rotated, names)
+			}
+		})
+	}
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * FOO *", "0 0 30 2 *", "@every 1h"} {
+		t.Run(expr, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "cron.log")
+			log := newFileLogger(&logger.FileTimeMode{Filename: filename, Cronexpr: expr})
+			log.Info("this is a info message")
+			log.Close()
+			if bs, _ := os.ReadFile(filename); len(bs) != 0 {
+				t.Fatalf("logged under the invalid expression %q:\n%s", expr, bs)
+			}
+		})
+	}
+}
+
+func TestRecordCountRotation(t *testing.T) {
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileCountMode{Filename: filepath.Join(dir, "count.log"), Maxrecords: 10})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				log.Info("this is a info message")
+			}
+		}()
+	}
+	wg.Wait()
+	log.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 20 {
+		t.Fatalf("expected 20 files, got %d", len(entries))
+	}
+	for _, e := range entries {
+		bs, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+		if n := strings.Count(string(bs), "\n"); n != 10 {
+			t.Fatalf("%s has %d records", e.Name(), n)
+		}
+	}
+}
+
+func TestRecordCountConcurrentLines(t *testing.T) {
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileCountMode{Filename: filepath.Join(dir, "lines.log"), Maxrecords: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Info("this is a info message")
+			log.Write([]byte("first line\nsecond line\n"))
+		}()
+	}
+	wg.Wait()
+	log.Close()
+
+	var total int
+	for _, name := range fileNames(dir) {
+		bs, _ := os.ReadFile(filepath.Join(dir, name))
+		s := string(bs)
+		n := strings.Count(s, "this is a info message\n") + strings.Count(s, "first line\nsecond line\n")
+		if total += n; n > 2 {
+			t.Fatalf("%s has %d records:\n%s", name, n, bs)
+		}
+		if strings.Count(s, "first line\nsecond line\n") != strings.Count(s, "first line") || strings.Count(s, "\n") != n+strings.Count(s, "first line") {
+			t.Fatalf("%s splits a write:\n%s", name, bs)
+		}
+	}
+	if total != 64*2 {
+		t.Fatalf("expected %d records, got %d", 64*2, total)
+	}
+}
+
+func TestRecordCountMultiLine(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "multi.log")
+	log := newFileLogger(&logger.FileCountMode{Filename: filename, Maxrecords: 3})
+	for i := 0; i < 4; i++ {
+		log.Infof("record %d\n\tat line 1\n\tat line 2", i)
+	}
+	log.Close()
+
+	names := fileNames(dir)
+	if len(names) != 2 {
+		t.Fatalf("expected the log file and one backup, got %v", names)
+	}
+	for _, name := range names {
+		bs, _ := os.ReadFile(filepath.Join(dir, name))
+		want := "record 3\n\tat line 1\n\tat line 2\n"
+		if name != "multi.log" {
+			want = "record 0\n\tat line 1\n\tat line 2\nrecord 1\n\tat line 1\n\tat line 2\nrecord 2\n\tat line 1\n\tat line 2\n"
+		}
+		if string(bs) != want {
+			t.Fatalf("unexpected %s:\n%s", name, bs)
+		}
+	}
+}
+
+type upperCompressor struct{}
+
+func (upperCompressor) Ext() string { return ".up" }
+
+func (upperCompressor) NewWriter(w io.Writer, name string) (io.WriteCloser, error) {
+	return upperWriter{w}, nil
+}
+
+type upperWriter struct{ io.Writer }
+
+func (w upperWriter) Write(bs []byte) (int, error) {
+	return w.Writer.Write([]byte(strings.ToUpper(string(bs))))
+}
+
+func (w upperWriter) Close() error { return nil }
+
+func TestCompressor(t *testing.T) {
+	for _, c := range []logger.Compressor{&logger.ZlibCompressor{Level: 9}, upperCompressor{}} {
+		dir := t.TempDir()
+		log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "zip.log"), Maxsize: 1 << 10, Compression: c})
+		writeLines(log, 50)
+		log.Close()
+
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 5 {
+			t.Fatalf("unexpected files: %v", entries)
+		}
+		for _, e := range entries {
+			if e.Name() == "zip.log" {
+				continue
+			}
+			if !strings.HasSuffix(e.Name(), c.Ext()) {
+				t.Fatalf("uncompressed backup: %s", e.Name())
+			}
+			f, _ := os.Open(filepath.Join(dir, e.Name()))
+			var r io.Reader = f
+			if _, ok := c.(upperCompressor); !ok {
+				zr, err := zlib.NewReader(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = zr
+			}
+			bs, _ := io.ReadAll(r)
+			f.Close()
+			if line := strings.ToLower(strings.Repeat("a", 99)) + "\n"; len(bs) == 0 || strings.ToLower(string(bs)) != strings.Repeat(line, len(bs)/len(line)) {
+				t.Fatalf("unexpected content of %s: %q", e.Name(), bs)
+			}
+		}
+	}
+}
+
+func TestRetentionOfCompressedBackups(t *testing.T) {
+	dir := t.TempDir()
+	for i, ext := range []string{".gz", ".zz", ""} {
+		os.WriteFile(filepath.Join(dir, fmt.Sprint("mixed_", i+1, ".log", ext)), []byte("backup"), 0666)
+	}
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "mixed.log"), Maxsize: 500, Maxbackup: 3})
+	log.Info(strings.Repeat("a", 600))
+	log.Info("this is a info message")
+	log.Close()
+
+	if names := strings.Join(fileNames(dir), ","); names != "mixed.log,mixed_2.log.zz,mixed_3.log,mixed_4.log" {
+		t.Fatalf("compressed backups were not counted with compression off: %s", names)
+	}
+}
+
+func TestGzipOnWithBackupName(t *testing.T) {
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "tpl.log"), Maxsize: 1 << 10, Maxbackup: 3, Backupname: "{name}.{seq}{ext}"})
+	log.SetGzipOn(true)
+	writeLines(log, 50)
+	log.Close() // waits for the compression in the background
+
+	if names := strings.Join(fileNames(dir), ","); names != "tpl.002.log.gz,tpl.003.log.gz,tpl.004.log.gz,tpl.log" {
+		t.Fatalf("unexpected files: %s", names)
+	}
+	if ls := readGzipLines(t, filepath.Join(dir, "tpl.004.log.gz")); len(ls) == 0 || ls[0] != strings.Repeat("a", 99) {
+		t.Fatalf("unexpected backup: %q", ls)
+	}
+}
+
+func TestCompressionRecovery(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 4; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("app.%03d.log", i)), []byte(fmt.Sprintln("backup", i)), 0666)
+	}
+	// leftovers of compressions interrupted by a crash
+	os.WriteFile(filepath.Join(dir, "app.001.log.gz.tmp"), []byte("partial"), 0666)
+	os.WriteFile(filepath.Join(dir, "app.002.log.gz"), []byte("partial"), 0666)
+
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "app.log"), Maxsize: 1 << 20, IsCompress: true, Keepuncompressed: 1, Backupname: "{name}.{seq}{ext}"})
+	log.Info("new file")
+	if _, err := log.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"app.001.log.gz", "app.002.log.gz", "app.003.log.gz", "app.004.log.gz", "app.005.log", "app.log"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i := 1; i <= 4; i++ {
+		f, _ := os.Open(filepath.Join(dir, fmt.Sprintf("app.%03d.log.gz", i)))
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, _ := io.ReadAll(zr)
+		f.Close()
+		if string(bs) != fmt.Sprintln("backup", i) {
+			t.Fatalf("unexpected content of backup %d: %q", i, bs)
+		}
+	}
+}
+
+func TestGzipActive(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log.gz")
+
+	// an incomplete member left by a crash is cut off
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("before crash\n"))
+	zw.Close()
+	complete := buf.Len()
+	zw = gzip.NewWriter(&buf)
+	zw.Write([]byte("lost"))
+	zw.Flush()
+	os.WriteFile(filename, buf.Bytes()[:complete+20], 0666)
+
+	log := newFileLogger(&logger.FileSizeMode{Filename: filename, Maxsize: 1 << 10, Gzipactive: true})
+	writeLines(log, 25)
+	log.Close()
+
+	entries, _ := os.ReadDir(dir)
+	lines := 0
+	for _, e := range entries {
+		ls := readGzipLines(t, filepath.Join(dir, e.Name()))
+		if e.Name() == "app.log_1.gz" {
+			if ls[0] != "before crash" {
+				t.Fatalf("unexpected first line: %q", ls[0])
+			}
+		}
+		lines += len(ls)
+	}
+	// Maxsize counts the uncompressed bytes by default
+	if len(entries) != 3 || lines != 26 {
+		t.Fatalf("unexpected files %v holding %d lines", entries, lines)
+	}
+}
+
+func TestGzipActiveSizeCompressed(t *testing.T) {
+	dir := t.TempDir()
+	log := newFileLogger(&logger.FileSizeMode{Filename: filepath.Join(dir, "app.log.gz"), Maxsize: 30, Gzipactive: true, Sizecompressed: true})
+	writeLines(log, 100)
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("rotated before the compressed size was known: %v", entries)
+	}
+	// the member ends a moment later, and its compressed size lands on disk
+	waitFor(t, 5*time.Second, func() bool {
+		fi, err := os.Stat(filepath.Join(dir, "app.log.gz"))
+		return err == nil && fi.Size() >= 30
+	})
+	log.Info("next")
+	log.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("unexpected files: %v", entries)
+	}
+	for _, e := range entries {
+		if e.Name() != "app.log.gz" {
+			if ls := readGzipLines(t, filepath.Join(dir, e.Name())); len(ls) != 100 {
+				t.Fatalf("backup holds %d lines", len(ls))
+			}
+		} else if ls := readGzipLines(t, filepath.Join(dir, e.Name())); len(ls) != 1 || ls[0] != "next" {
+			t.Fatalf("unexpected active file: %q", ls)
+		}
+	}
+}
+
+func TestGzipActiveFromPlainFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	os.WriteFile(filename, []byte("written before Gzipactive was set\n"), 0666)
+
+	log := newFileLogger(&logger.FileSizeMode{Filename: filename, Maxsize: 1 << 10, Gzipactive: true})
+	writeLines(log, 15)
+	log.Close()
+
+	if names := strings.Join(fileNames(dir), ","); names != "app.log,app_1.log,app_2.log.gz" {
+		t.Fatalf("unexpected files: %s", names)
+	}
+	if bs, _ := os.ReadFile(filepath.Join(dir, "app_1.log")); string(bs) != "written before Gzipactive was set\n" {
+		t.Fatalf("the plain file was not kept: %q", bs)
+	}
+	if ls := readGzipLines(t, filepath.Join(dir, "app_2.log.gz")); len(ls) != 11 {
+		t.Fatalf("backup holds %d lines", len(ls))
+	}
+	if ls := readGzipLines(t, filepath.Join(dir, "app.log")); len(ls) != 4 {
+		t.Fatalf("active file holds %d lines", len(ls))
+	}
+}
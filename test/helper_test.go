@@ -0,0 +1,116 @@
+package test
+
+import (
+	"compress/gzip"
+	"github.com/donnie4w/go-logger/logger"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRingLogger returns a logger that records into a ring buffer of size records instead of the console.
+func newRingLogger(size int) (*logger.Logging, *logger.RingBuffer) {
+	log := logger.NewLogger()
+	log.SetConsole(false)
+	rb := logger.NewRingBuffer(size, 0)
+	log.AddSink(rb)
+	return log, rb
+}
+
+// newFileLogger returns a logger that writes bare messages into the file of option only.
+func newFileLogger(option logger.FileOption) *logger.Logging {
+	log := logger.NewLogger()
+	log.SetOption(&logger.Option{Format: logger.FORMAT_NANO, Console: false, FileOption: option})
+	return log
+}
+
+// writeLines logs n lines of 100 bytes, the newline included.
+func writeLines(log *logger.Logging, n int) {
+	line := strings.Repeat("a", 99)
+	for i := 0; i < n; i++ {
+		log.Info(line)
+	}
+}
+
+func isFileExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// fileNames returns the names of the entries of dir in order.
+func fileNames(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// waitFor polls cond until it holds, failing the test after timeout.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the condition")
+		}
+	}
+}
+
+// useZone runs the local clock in the time zone name and restores the zone and logger.TIME_DEVIATION when
+// the test ends. The test is skipped if the zone database is missing.
+func useZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip(err)
+	}
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local, logger.TIME_DEVIATION = local, 0 })
+	return loc
+}
+
+// setClock moves the logger clock to tm.
+func setClock(tm time.Time) {
+	logger.TIME_DEVIATION = time.Until(tm)
+}
+
+// logAt logs msg with the logger clock at tm, failing the test if the write does not return in time.
+func logAt(t *testing.T, log *logger.Logging, tm time.Time, msg string) {
+	t.Helper()
+	setClock(tm)
+	within(t, func() { log.Info(msg) })
+}
+
+// within runs f, failing the test if it does not return in time.
+func within(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() { f(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("does not return in time")
+	}
+}
+
+// readGzipLines returns the lines of the gzip file at path.
+func readGzipLines(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+}